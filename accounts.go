@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"path"
+)
+
+// accountAuth returns the auth options of a merged account. Accounts are stored like the profiles, so they can be
+// set up with setup --profile <name>.
+func accountAuth(authOpts auth.Options, account string) auth.Options {
+	if account != "" {
+		authOpts.ConfigDir = path.Join(authOpts.ConfigDir, "profiles", account)
+	}
+	return authOpts
+}
+
+// accounts returns the names of the merged accounts, or one empty name for the default account.
+func accounts(opts runOptions) []string {
+	if len(opts.accounts) == 0 {
+		return []string{""}
+	}
+	return opts.accounts
+}
+
+// newService creates the calendar client of an account, replaced by the tests.
+var newService = auth.NewService
+
+// newCalendars creates the API clients of the accounts and returns the selected calendars of them.
+func newCalendars(ctx context.Context, authOpts auth.Options, opts runOptions) ([]providers.Google, error) {
+	var syncDir string
+	if opts.incrementalSync {
+		dir, err := cacheDir(opts.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		syncDir = dir
+	}
+	var res []providers.Google
+	for _, account := range accounts(opts) {
+		service, err := newService(ctx, accountAuth(authOpts, account))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range opts.calendars {
+			res = append(res, providers.Google{
+				Service: service,
+				ID:      id,
+				Account: account,
+
+				SharedPropertyFilter:  opts.sharedPropertyFilter,
+				PrivatePropertyFilter: opts.privatePropertyFilter,
+				SyncDir:               syncDir,
+			})
+		}
+	}
+	return res, nil
+}
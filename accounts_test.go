@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSingleClientPerAccount(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	created := 0
+	original := newService
+	defer func() { newService = original }()
+	newService = func(ctx context.Context, authOpts auth.Options) (*calendar.Service, error) {
+		created++
+		return calendar.NewService(ctx, option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	}
+
+	tests := []struct {
+		name     string
+		accounts []string
+		expected int
+	}{
+		{name: "default account", expected: 1},
+		{name: "merged accounts", accounts: []string{"work", "home"}, expected: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			created = 0
+			opts := runOptions{
+				google:    true,
+				calendars: []string{"primary", "team", "holidays"},
+				accounts:  tc.accounts,
+			}
+			ctx := context.Background()
+			calendars, err := newSources(ctx, auth.Options{ConfigDir: t.TempDir()}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(calendars) != 3*len(accounts(opts)) {
+				t.Fatalf("unexpected number of calendars: %d", len(calendars))
+			}
+			// consecutive polls of watch reuse the same sources
+			for i := 0; i < 3; i++ {
+				if _, err := fetch(ctx, calendars, time.Now(), opts); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if created != tc.expected {
+				t.Fatalf("expected %d clients, got %d", tc.expected, created)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// agenda prints the events of the day in aligned columns, with a marker at the current time.
+func agenda(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	events, err := fetch(ctx, calendars, now, opts)
+	if err != nil {
+		return err
+	}
+	return writeAgenda(os.Stdout, events, now, opts.Options)
+}
+
+func writeAgenda(output io.Writer, events []render.Event, now time.Time, opts render.Options) error {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	if len(events) == 0 {
+		_, _ = fmt.Fprintln(w, "No events")
+	}
+	marked := false
+	for _, event := range events {
+		if event.AllDay {
+			_, _ = fmt.Fprintf(w, " \tall day\t\t%s\n", event.Raw.Summary)
+			continue
+		}
+		if !marked && now.Before(event.Start) {
+			_, _ = fmt.Fprintf(w, "▸\t%s\t\tnow\n", render.FormatClock(now, opts))
+			marked = true
+		}
+		marker := " "
+		if event.InProgress(now) {
+			marker = "▶"
+			marked = true
+		}
+		clock := render.FormatClock(event.Start, opts)
+		duration := ""
+		if !event.End.IsZero() {
+			clock += "–" + render.FormatClock(event.End, opts)
+			duration = render.ShortDuration(event.End.Sub(event.Start))
+		}
+		summary := event.Raw.Summary
+		if event.Declined() {
+			summary += " (declined)"
+		}
+		if link := event.VideoLink(); link != "" {
+			summary += "  " + link
+		} else if room := event.Room(); room != "" {
+			summary += "  @ " + room
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, clock, duration, summary)
+	}
+	if !marked && len(events) > 0 {
+		_, _ = fmt.Fprintf(w, "▸\t%s\t\tnow\n", render.FormatClock(now, opts))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// eventCache is the saved result of one event list query.
+type eventCache struct {
+	Fetched  time.Time         `json:"fetched"`
+	Calendar string            `json:"calendar"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Events   []*calendar.Event `json:"events"`
+}
+
+// eventCacheFile returns the cache file of a query. Queries of the same day with the same length share the file.
+func eventCacheFile(dir string, calendarID string, from time.Time, to time.Time) string {
+	return filepath.Join(dir, fmt.Sprintf("events-%s-%s-%dm.json", url.PathEscape(calendarID), from.Format("20060102"), int(to.Sub(from).Minutes())))
+}
+
+// pruneEventCache removes the old cache files of the calendar.
+func pruneEventCache(dir string, calendarID string) {
+	files, err := filepath.Glob(filepath.Join(dir, "events-"+url.PathEscape(calendarID)+"-*.json"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > 48*time.Hour {
+			_ = os.Remove(file)
+		}
+	}
+}
+
+// readEventCache returns the cached events or nil if they are not cached. Corrupt cache files are removed.
+func readEventCache(file string) *eventCache {
+	cached := &eventCache{}
+	if !osutil.ReadCache(file, cached) {
+		return nil
+	}
+	return cached
+}
+
+// cacheWarm refreshes the tokens and saves the events of the day to the cache directory.
+func cacheWarm(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	// always fetch, the cache is written by fetchCalendar
+	opts.cacheTTL = 0
+	opts.offlineFallback = true
+	from, to := queryWindow(time.Now(), opts)
+
+	for _, account := range accounts(opts) {
+		if err := refreshToken(ctx, accountAuth(authOpts, account)); err != nil {
+			return err
+		}
+		service, err := newService(ctx, accountAuth(authOpts, account))
+		if err != nil {
+			return err
+		}
+		for _, id := range opts.calendars {
+			cal := providers.Google{Service: service, ID: id, Account: account}
+			_, stale, err := fetchCalendar(ctx, cal, from, to, opts)
+			if err != nil {
+				return err
+			}
+			if stale {
+				return errs.Errorf("events of %s couldn't be fetched", cal.Name())
+			}
+		}
+	}
+	return nil
+}
+
+// refreshToken refreshes and saves the token, even if it's not expired yet.
+func refreshToken(ctx context.Context, authOpts auth.Options) error {
+	config, err := auth.ReadCredentials(authOpts)
+	if err != nil {
+		return err
+	}
+	token, err := auth.ReadToken(authOpts)
+	if err != nil {
+		return err
+	}
+	token.Expiry = time.Now().Add(-time.Hour)
+	token, err = config.TokenSource(ctx, token).Token()
+	if err != nil {
+		return errs.Errorf("token couldn't be refreshed: %v", err)
+	}
+	return auth.WriteToken(authOpts, token)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadEventCache(t *testing.T) {
+	fetched := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	valid := eventCache{
+		Fetched:  fetched,
+		Calendar: "primary",
+		Events:   []*calendar.Event{{Id: "1", Summary: "Standup"}},
+	}
+
+	tests := []struct {
+		name    string
+		content func(file string) error
+		found   bool
+		removed bool
+	}{
+		{
+			name:    "valid",
+			content: func(file string) error { return osutil.WriteJSON(file, valid) },
+			found:   true,
+		},
+		{
+			name:    "missing",
+			content: func(file string) error { return nil },
+			removed: true,
+		},
+		{
+			name:    "empty",
+			content: func(file string) error { return ioutil.WriteFile(file, nil, 0600) },
+			removed: true,
+		},
+		{
+			name: "truncated",
+			content: func(file string) error {
+				if err := osutil.WriteJSON(file, valid); err != nil {
+					return err
+				}
+				info, err := os.Stat(file)
+				if err != nil {
+					return err
+				}
+				return os.Truncate(file, info.Size()/2)
+			},
+			removed: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "events.json")
+			if err := tc.content(file); err != nil {
+				t.Fatal(err)
+			}
+			cached := readEventCache(file)
+			if (cached != nil) != tc.found {
+				t.Fatalf("expected found=%v, got %+v", tc.found, cached)
+			}
+			if tc.found && (!cached.Fetched.Equal(fetched) || len(cached.Events) != 1 || cached.Events[0].Summary != "Standup") {
+				t.Fatalf("unexpected cache content %+v", cached)
+			}
+			if _, err := os.Stat(file); os.IsNotExist(err) != tc.removed {
+				t.Fatalf("expected removed=%v, got %v", tc.removed, err)
+			}
+		})
+	}
+}
+
+func TestCacheWarm(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			_, _ = w.Write([]byte(`{"access_token":"fresh","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"items":[{"id":"1","summary":"Standup","start":{"dateTime":"2021-06-01T10:00:00Z"},"end":{"dateTime":"2021-06-01T10:15:00Z"}}]}`))
+	}))
+	defer server.Close()
+
+	configDir := t.TempDir()
+	credentials := `{"installed":{"client_id":"id","client_secret":"secret","auth_uri":"` + server.URL + `/auth","token_uri":"` + server.URL + `/token","redirect_uris":["http://localhost"]}}`
+	if err := ioutil.WriteFile(filepath.Join(configDir, "credentials.json"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authOpts := auth.Options{ConfigDir: configDir}
+	if err := auth.WriteToken(authOpts, &oauth2.Token{AccessToken: "old", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	original := newService
+	defer func() { newService = original }()
+	newService = func(ctx context.Context, authOpts auth.Options) (*calendar.Service, error) {
+		token, err := auth.ReadToken(authOpts)
+		if err != nil {
+			return nil, err
+		}
+		return calendar.NewService(ctx, append(auth.ServiceOptions(oauth2.StaticTokenSource(token), ""), option.WithEndpoint(server.URL+"/"))...)
+	}
+
+	opts := runOptions{calendars: []string{"primary", "team"}, cacheDir: t.TempDir()}
+	if err := cacheWarm(authOpts, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := auth.ReadToken(authOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "fresh" || token.RefreshToken != "refresh" {
+		t.Fatalf("the refreshed token is not saved, got %+v", token)
+	}
+	if authorization != "Bearer fresh" {
+		t.Fatalf("the events are not fetched with the refreshed token, got %q", authorization)
+	}
+	from, to := queryWindow(time.Now(), opts)
+	for _, id := range opts.calendars {
+		cached := readEventCache(eventCacheFile(opts.cacheDir, id, from, to))
+		if cached == nil || len(cached.Events) != 1 || cached.Events[0].Summary != "Standup" {
+			t.Fatalf("events of %s are not cached: %+v", id, cached)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs/v2"
+	"os"
+	"sort"
+)
+
+// applyConfigFile sets the flags of the command from the config file. Flags from the command line win.
+// Keys of the file are the flag names (like calendar = ["primary"] or fetch-timeout-per-calendar = "5s").
+func applyConfigFile(cmd *cobra.Command, file string) error {
+	values := map[string]interface{}{}
+	_, err := toml.DecodeFile(file, &values)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errs.Errorf("couldn't read config file %s: %v", file, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if contains(providers.ConfigSections, key) {
+			continue
+		}
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			if !knownFlag(cmd.Root(), key) {
+				return errs.Errorf("unknown option %q in config file %s", key, file)
+			}
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		var items []interface{}
+		switch value := values[key].(type) {
+		case []interface{}:
+			items = value
+		default:
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			if err := cmd.Flags().Set(key, fmt.Sprint(item)); err != nil {
+				return errs.Errorf("invalid value of %q in config file %s: %v", key, file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// knownFlag returns true if any of the commands has a flag with the name.
+func knownFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+		return true
+	}
+	for _, child := range cmd.Commands() {
+		if knownFlag(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Exit codes of the scripting friendly commands.
+const (
+	exitImminent     = 0
+	exitFree         = 1
+	exitAuthError    = 2
+	exitNetworkError = 3
+	exitOtherError   = 4
+)
+
+// exitError is returned by a command to exit with the code, printing the error only if it's set.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit code %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// count prints the number of the remaining (not yet finished) timed events of the day. It exits with 0 if a meeting
+// is in progress or imminent, 1 if free, 2 on authentication error, 3 on network error and 4 on other errors.
+func count(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		// the sources can't be created mostly because of missing or invalid credentials and tokens
+		code := scriptExitCode(err)
+		if code == exitOtherError {
+			code = exitAuthError
+		}
+		return &exitError{code: code, err: err}
+	}
+	now := time.Now()
+	events, err := fetch(ctx, calendars, now, opts)
+	if err != nil {
+		return &exitError{code: scriptExitCode(err), err: err}
+	}
+	remaining := 0
+	for _, event := range events {
+		if !event.AllDay && !event.Finished(now) {
+			remaining++
+		}
+	}
+	fmt.Println(remaining)
+
+	item, err := render.Render(events, now, opts.Options)
+	if err != nil {
+		return &exitError{code: exitOtherError, err: err}
+	}
+	if item.InProgress || contains(item.Class, "imminent") {
+		return nil
+	}
+	return &exitError{code: exitFree}
+}
+
+// scriptExitCode classifies the error as authentication, network or other error.
+func scriptExitCode(err error) int {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) || errors.Is(err, os.ErrNotExist) {
+		return exitAuthError
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden:
+			return exitAuthError
+		case apiErr.Code >= 500:
+			return exitNetworkError
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return exitNetworkError
+	}
+	return exitOtherError
+}
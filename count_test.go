@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestScriptExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "expired refresh token", err: errs.Wrap(&oauth2.RetrieveError{}), expected: exitAuthError},
+		{name: "missing token", err: errs.Wrap(&os.PathError{Op: "open", Path: "token.json", Err: os.ErrNotExist}), expected: exitAuthError},
+		{name: "forbidden", err: errs.Wrap(&googleapi.Error{Code: 403}), expected: exitAuthError},
+		{name: "server error", err: errs.Wrap(&googleapi.Error{Code: 503}), expected: exitNetworkError},
+		{name: "not found", err: errs.Wrap(&googleapi.Error{Code: 404}), expected: exitOtherError},
+		{name: "offline", err: errs.Wrap(&url.Error{Op: "Get", URL: "https://www.googleapis.com", Err: &net.OpError{Op: "dial"}}), expected: exitNetworkError},
+		{name: "other", err: errs.Errorf("invalid config"), expected: exitOtherError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := scriptExitCode(tc.err); got != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
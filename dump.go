@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"os"
+	"time"
+)
+
+// dumpedEvent is the normalized event in the output of dump.
+type dumpedEvent struct {
+	ID        string           `json:"id"`
+	Summary   string           `json:"summary"`
+	Start     time.Time        `json:"start"`
+	End       time.Time        `json:"end"`
+	AllDay    bool             `json:"all_day"`
+	Location  string           `json:"location,omitempty"`
+	Link      string           `json:"link,omitempty"`
+	HTMLLink  string           `json:"html_link,omitempty"`
+	Status    string           `json:"status,omitempty"`
+	Response  string           `json:"response,omitempty"`
+	Attendees []dumpedAttendee `json:"attendees"`
+	Calendar  string           `json:"calendar"`
+}
+
+type dumpedAttendee struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	Response string `json:"response,omitempty"`
+	Self     bool   `json:"self,omitempty"`
+}
+
+func newDumpedEvent(event render.Event) dumpedEvent {
+	res := dumpedEvent{
+		ID:        event.Raw.Id,
+		Summary:   event.Raw.Summary,
+		Start:     event.Start,
+		End:       event.End,
+		AllDay:    event.AllDay,
+		Location:  event.Raw.Location,
+		Link:      event.VideoLink(),
+		HTMLLink:  event.Raw.HtmlLink,
+		Status:    event.Raw.Status,
+		Response:  event.ResponseStatus(),
+		Attendees: []dumpedAttendee{},
+		Calendar:  event.Calendar,
+	}
+	for _, attendee := range event.Raw.Attendees {
+		res.Attendees = append(res.Attendees, dumpedAttendee{
+			Email:    attendee.Email,
+			Name:     attendee.DisplayName,
+			Response: attendee.ResponseStatus,
+			Self:     attendee.Self,
+		})
+	}
+	return res
+}
+
+// dump prints the fetched and filtered events of the day as a JSON array.
+func dump(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	events, err := fetch(ctx, calendars, time.Now(), opts)
+	if err != nil {
+		return err
+	}
+	res := []dumpedEvent{}
+	for _, event := range events {
+		res = append(res, newDumpedEvent(event))
+	}
+	output := json.NewEncoder(os.Stdout)
+	output.SetIndent("", "  ")
+	return output.Encode(res)
+}
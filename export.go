@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type exportOptions struct {
+	csv  bool
+	ics  bool
+	file string
+}
+
+// export writes the fetched and filtered events of the day to the output file (or stdout).
+func export(authOpts auth.Options, opts runOptions, eopts exportOptions) error {
+	if eopts.csv == eopts.ics {
+		return errs.Errorf("use one of --csv and --ics")
+	}
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	events, err := fetch(ctx, calendars, time.Now(), opts)
+	if err != nil {
+		return err
+	}
+
+	var output io.Writer = os.Stdout
+	if eopts.file != "" {
+		file, err := os.Create(eopts.file)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		defer func() { _ = file.Close() }()
+		output = file
+	}
+	if eopts.ics {
+		return writeICS(output, events, time.Now())
+	}
+	return writeCSV(output, events)
+}
+
+// writeCSV writes the start, end, summary, location and link columns of the events.
+func writeCSV(output io.Writer, events []render.Event) error {
+	w := csv.NewWriter(output)
+	if err := w.Write([]string{"start", "end", "summary", "location", "link"}); err != nil {
+		return errs.Wrap(err)
+	}
+	for _, event := range events {
+		layout := "2006-01-02 15:04"
+		if event.AllDay {
+			layout = "2006-01-02"
+		}
+		end := ""
+		if !event.End.IsZero() {
+			end = event.End.Format(layout)
+		}
+		err := w.Write([]string{event.Start.Format(layout), end, event.Raw.Summary, event.Raw.Location, event.VideoLink()})
+		if err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	w.Flush()
+	return errs.Wrap(w.Error())
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// writeICS writes the events as an iCalendar file. Only the time, summary, location, link and status of the events
+// are exported, the description and the attendees are left out.
+func writeICS(output io.Writer, events []render.Event, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//elek//waybar-google-calendar-check//EN",
+	}
+	for _, event := range events {
+		uid := event.Raw.ICalUID
+		if uid == "" {
+			uid = event.Raw.Id
+		}
+		if event.Raw.RecurringEventId != "" {
+			// the expanded occurrences of the recurring events are exported as separate events
+			uid += "-" + event.Start.UTC().Format("20060102T150405Z")
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+icsTextEscaper.Replace(uid),
+			"DTSTAMP:"+now.UTC().Format("20060102T150405Z"),
+		)
+		if event.AllDay {
+			lines = append(lines, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+			if !event.End.IsZero() {
+				lines = append(lines, "DTEND;VALUE=DATE:"+event.End.Format("20060102"))
+			}
+		} else {
+			lines = append(lines, "DTSTART:"+event.Start.UTC().Format("20060102T150405Z"))
+			if !event.End.IsZero() {
+				lines = append(lines, "DTEND:"+event.End.UTC().Format("20060102T150405Z"))
+			}
+		}
+		lines = append(lines, "SUMMARY:"+icsTextEscaper.Replace(event.Raw.Summary))
+		if event.Raw.Location != "" {
+			lines = append(lines, "LOCATION:"+icsTextEscaper.Replace(event.Raw.Location))
+		}
+		if link := event.VideoLink(); link != "" {
+			lines = append(lines, "URL:"+link)
+		}
+		if status := strings.ToUpper(event.Raw.Status); status != "" {
+			lines = append(lines, "STATUS:"+status)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	w := bufio.NewWriter(output)
+	for _, line := range lines {
+		if _, err := w.WriteString(foldICSLine(line) + "\r\n"); err != nil {
+			return errs.Wrap(err)
+		}
+	}
+	return errs.Wrap(w.Flush())
+}
+
+// foldICSLine splits the content lines longer than 75 octets, without breaking the UTF-8 characters.
+func foldICSLine(line string) string {
+	var res strings.Builder
+	length := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if length+size > 75 {
+			res.WriteString("\r\n ")
+			length = 1
+		}
+		res.WriteRune(r)
+		length += size
+	}
+	return res.String()
+}
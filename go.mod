@@ -3,10 +3,11 @@ module github.com/elek/waybar-google-calendar-check
 go 1.15
 
 require (
-	github.com/spf13/cobra v1.4.0 // indirect
-	github.com/zeebo/errs/v2 v2.0.3 // indirect
-	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
-	golang.org/x/sys v0.0.0-20200831180312-196b9ba8737a // indirect
-	google.golang.org/api v0.31.0
-	google.golang.org/genproto v0.0.0-20200901141002-b3bf27a9dbd1 // indirect
+	github.com/BurntSushi/toml v0.4.1
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/spf13/cobra v1.4.0
+	github.com/teambition/rrule-go v1.8.2
+	github.com/zeebo/errs/v2 v2.0.3
+	golang.org/x/oauth2 v0.0.0-20210402161424-2e8d93401602
+	google.golang.org/api v0.44.0
 )
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hook is a shell command executed at one point of the lifecycle of the events.
+type hook struct {
+	// kind is before-start, start or end.
+	kind    string
+	offset  time.Duration
+	command string
+}
+
+// parseHook parses the before-start:5m=command, start=command and end=command hook definitions.
+func parseHook(spec string) (hook, error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return hook{}, errs.Errorf("invalid hook %q, use before-start:5m=command, start=command or end=command", spec)
+	}
+	h := hook{command: parts[1]}
+	kind := strings.SplitN(parts[0], ":", 2)
+	h.kind = kind[0]
+	switch {
+	case h.kind == "before-start" && len(kind) == 2:
+		offset, err := time.ParseDuration(kind[1])
+		if err != nil {
+			return hook{}, errs.Errorf("invalid offset of hook %q: %v", spec, err)
+		}
+		h.offset = offset
+	case (h.kind == "start" || h.kind == "end") && len(kind) == 1:
+	default:
+		return hook{}, errs.Errorf("invalid hook %q, use before-start:5m=command, start=command or end=command", spec)
+	}
+	return h, nil
+}
+
+// due returns when the hook should be executed for the event, and the end of the period when it's still worth to
+// execute it (eg. after a wake up from suspend).
+func (h hook) due(event render.Event) (at time.Time, until time.Time) {
+	switch h.kind {
+	case "before-start":
+		return event.Start.Add(-h.offset), event.Start
+	case "start":
+		return event.Start, event.End
+	default:
+		return event.End, event.End.Add(time.Hour)
+	}
+}
+
+// hooks executes the configured commands of the events. Only the hooks due after the start of the daemon are
+// executed, each at most once.
+type hooks struct {
+	hooks   []hook
+	started time.Time
+	// done contains the due time of the already executed hooks.
+	done map[string]time.Time
+}
+
+func newHooks(specs []string, now time.Time) (*hooks, error) {
+	h := &hooks{
+		started: now,
+		done:    map[string]time.Time{},
+	}
+	for _, spec := range specs {
+		parsed, err := parseHook(spec)
+		if err != nil {
+			return nil, err
+		}
+		h.hooks = append(h.hooks, parsed)
+	}
+	return h, nil
+}
+
+func (h *hooks) check(events []render.Event, now time.Time) {
+	for key, at := range h.done {
+		if now.Sub(at) > 24*time.Hour {
+			delete(h.done, key)
+		}
+	}
+	for _, event := range events {
+		if event.AllDay || event.End.IsZero() || event.Declined() {
+			continue
+		}
+		for i, hk := range h.hooks {
+			at, until := hk.due(event)
+			key := fmt.Sprintf("%d/%s/%s", i, event.Raw.Id, at.Format(time.RFC3339))
+			if _, done := h.done[key]; done || at.Before(h.started) || now.Before(at) || !now.Before(until) {
+				continue
+			}
+			h.done[key] = at
+			hk.execute(event)
+		}
+	}
+}
+
+// execute starts the command in the background with the details of the event in environment variables.
+func (h hook) execute(event render.Event) {
+	cmd := exec.Command("sh", "-c", h.command)
+	cmd.Env = append(os.Environ(),
+		"HOOK="+h.kind,
+		"EVENT_ID="+event.Raw.Id,
+		"EVENT_SUMMARY="+event.Raw.Summary,
+		"EVENT_START="+event.Start.Format(time.RFC3339),
+		"EVENT_END="+event.End.Format(time.RFC3339),
+		"EVENT_LINK="+event.VideoLink(),
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Printf("couldn't execute %s hook of %s: %v", h.kind, event.Raw.Summary, err)
+		return
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Printf("%s hook of %s failed: %v", h.kind, event.Raw.Summary, err)
+		}
+	}()
+}
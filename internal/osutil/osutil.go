@@ -0,0 +1,94 @@
+// Package osutil contains the file and process helpers shared by the packages.
+package osutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/zeebo/errs/v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ReadCache reads a JSON file of the cache directory. Returns false if the file is missing or can't be read. Corrupt
+// files (eg. partially written because the disk is full) are logged and removed, to be handled as a cache miss.
+func ReadCache(file string, value interface{}) bool {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("couldn't read cache file %s: %v", file, err)
+		}
+		return false
+	}
+	if err := json.Unmarshal(content, value); err != nil {
+		log.Printf("removing corrupt cache file %s: %v", file, err)
+		_ = os.Remove(file)
+		return false
+	}
+	return true
+}
+
+// WriteJSON saves the value as JSON, readable only by the user.
+func WriteJSON(file string, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	return WriteFileAtomic(file, content, 0600)
+}
+
+// WriteFileAtomic replaces the file with the new content, without leaving a partially written file behind. Each call
+// writes its own temporary file, so concurrent writers can't rename each other's partial content.
+func WriteFileAtomic(file string, content []byte, perm os.FileMode) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err := tmp.Chmod(perm); err != nil {
+		return errs.Wrap(err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		return errs.Wrap(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return errs.Wrap(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return errs.Wrap(err)
+	}
+	return nil
+}
+
+// OpenLink opens the link with the default application (xdg-open).
+func OpenLink(link string) error {
+	if link == "" {
+		return errs.Errorf("no link to open")
+	}
+	return errs.Wrap(exec.Command("xdg-open", link).Run())
+}
+
+// CommandOutput executes the command and returns its output, with the error output in the error message.
+func CommandOutput(cmd *exec.Cmd) ([]byte, error) {
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errs.Errorf("%v: %s", err, msg)
+		}
+		return nil, errs.Wrap(err)
+	}
+	return out, nil
+}
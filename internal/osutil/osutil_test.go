@@ -0,0 +1,134 @@
+package osutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestReadCache(t *testing.T) {
+	type value struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		found    bool
+		expected value
+	}{
+		{name: "valid", content: `{"name":"a","count":2}`, found: true, expected: value{Name: "a", Count: 2}},
+		{name: "truncated", content: `{"name":"a","cou`},
+		{name: "wrong type", content: `{"name":1}`},
+		{name: "empty", content: ``},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "cache.json")
+			if err := ioutil.WriteFile(file, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			var got value
+			if found := ReadCache(file, &got); found != tc.found {
+				t.Fatalf("expected found=%v", tc.found)
+			}
+			if tc.found && got != tc.expected {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+			// corrupt files are removed, to be rewritten by the next fetch
+			if _, err := os.Stat(file); os.IsNotExist(err) == tc.found {
+				t.Fatalf("corrupt file should be removed, valid one kept (%v)", err)
+			}
+		})
+	}
+
+	var got value
+	if ReadCache(filepath.Join(t.TempDir(), "missing.json"), &got) {
+		t.Fatal("missing file shouldn't be found")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "state.json")
+	if err := WriteFileAtomic(file, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(file, []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "second" {
+		t.Fatalf("unexpected content %q", content)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Fatalf("unexpected permissions %s", info.Mode())
+	}
+}
+
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "state.json")
+	var wg sync.WaitGroup
+	errors := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := []byte(fmt.Sprintf("%02d%s", i, make([]byte, 64*1024)))
+			errors <- WriteFileAtomic(file, content, 0600)
+		}(i)
+	}
+	wg.Wait()
+	close(errors)
+	for err := range errors {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content) != 2+64*1024 {
+		t.Fatalf("file is written partially: %d bytes", len(content))
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("temporary files are left behind: %d files", len(files))
+	}
+}
+
+func TestWriteFileAtomicRemovesTemporaryFile(t *testing.T) {
+	dir := t.TempDir()
+	// the target is a non-empty directory, it can't be replaced by the rename
+	target := filepath.Join(dir, "target")
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(target, []byte("content"), 0600); err == nil {
+		t.Fatal("rename should fail")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("temporary file is left behind: %d files", len(files))
+	}
+}
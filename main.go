@@ -3,34 +3,247 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
 	"github.com/spf13/cobra"
 	"github.com/zeebo/errs/v2"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/user"
 	"path"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// version is the version of the binary, set during the build with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	cmd := cobra.Command{}
-	configDir := cmd.PersistentFlags().String("config-dir", "${HOME}/.config/waybar-google-calendar-check", "Directory to store the tokens (and credentials)")
+	configDir := cmd.PersistentFlags().String("config-dir", "${HOME}/.config/"+auth.AppName, "Directory to store the tokens (and credentials)")
+	configFile := cmd.PersistentFlags().String("config", "", "Config file with the default values of the flags (default is config.toml in the config dir)")
+	userAgent := cmd.PersistentFlags().String("api-user-agent", auth.AppName+"/"+version, "User agent (and application name) used for the Google API calls")
+	tokenKeyCmd := cmd.PersistentFlags().String("token-key-cmd", "", "Command which prints the key used to encrypt the saved token (eg. secret-tool lookup ...)")
+	profile := cmd.PersistentFlags().String("profile", "", "Name of the profile (eg. work), using its own credentials, token, config.toml and cache under <config-dir>/profiles/<name>")
+	timezone := cmd.PersistentFlags().String("timezone", "", "Show the times and compute the day in this timezone (eg. Europe/Berlin) instead of the system one")
+	tokenStore := cmd.PersistentFlags().String("token-store", "file", "Where to save the token: file (token.json in the config dir), keyring (Secret Service, falls back to the file), pass or gpg (token.json.gpg in the config dir)")
+	passName := cmd.PersistentFlags().String("pass-name", auth.AppName+"/token", "Name of the token in the password store (with --token-store=pass)")
+	gpgRecipient := cmd.PersistentFlags().String("gpg-recipient", "", "Key used to encrypt the token (with --token-store=gpg)")
+	scopes := cmd.PersistentFlags().StringSlice("scopes", []string{"calendar.readonly"}, "OAuth scopes to request during setup (eg. calendar.readonly,calendar.events)")
+	effectiveConfigFile := func() string {
+		if *configFile == "" {
+			return path.Join(getConfigDir(*configDir), "config.toml")
+		}
+		return *configFile
+	}
+	authOptions := func() auth.Options {
+		return auth.Options{
+			ConfigDir:    getConfigDir(*configDir),
+			ConfigFile:   effectiveConfigFile(),
+			UserAgent:    *userAgent,
+			TokenKeyCmd:  *tokenKeyCmd,
+			Scopes:       *scopes,
+			TokenStore:   *tokenStore,
+			PassName:     *passName,
+			GPGRecipient: *gpgRecipient,
+		}
+	}
 	{
 		subCmd := cobra.Command{
 			Use:   "run",
 			Short: "Check gmail inbox and return the unread information in waybar format.",
 		}
-		calendar := subCmd.Flags().String("calendar", "", "Identifier of the calendar (use list to print out available options")
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return run(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:     "watch",
+			Aliases: []string{"daemon"},
+			Short:   "Keep running and print one waybar item per line after each refresh (for waybar exec without interval)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		wopts := watchOptions{}
+		subCmd.Flags().DurationVar(&wopts.interval, "interval", time.Minute, "Time between two refreshes")
+		subCmd.Flags().DurationVar(&wopts.jitter, "poll-jitter", 0, "Randomize the interval with +/- this duration to spread the load")
+		subCmd.Flags().BoolVar(&wopts.blink, "headline-blink-when-now", false, "Toggle the blink class between the polls while the event is in progress")
+		subCmd.Flags().IntVar(&wopts.blinkEvery, "blink-every", 1, "Number of polls between toggling the blink class")
+		subCmd.Flags().DurationSliceVar(&wopts.remindBefore, "remind-before", nil, "Send desktop notification this long before the events (can be repeated)")
+		subCmd.Flags().StringVar(&wopts.notifyBackend, "notify-backend", "notify-send", "Backend of the notifications (notify-send or dbus)")
+		subCmd.Flags().BoolVar(&wopts.suppressInOOO, "suppress-reminders-during-ooo", false, "Don't send notifications during out-of-office events")
+		subCmd.Flags().StringArrayVar(&wopts.hooks, "hook", nil, "Shell command to execute at before-start:<offset>, start or end of the events, eg. start=\"pactl set-sink-mute @DEFAULT_SINK@ 0\" (can be repeated)")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return watch(authOptions(), opts, wopts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "open",
+			Short: "Open the next event in the browser (or its location in the maps application)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		preferLocation := subCmd.Flags().Bool("prefer-location", false, "Open the geo: URI of the location if it has coordinates or a maps link")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return open(authOptions(), opts, *preferLocation)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "join",
+			Short: "Open the video conference of the running or next meeting (eg. as waybar on-click)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		preferLocation := subCmd.Flags().Bool("prefer-location", false, "Open the geo: URI of the location (instead of the conference) if it has coordinates or a maps link")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return join(authOptions(), opts, *preferLocation)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "copy-link",
+			Short: "Copy the conference link of the running or next meeting to the clipboard (with wl-copy or xclip)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return copyLink(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "dump",
+			Short: "Print the events of the day (after the filtering) as JSON, for scripts",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return dump(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "export",
+			Short: "Export the events of the day (after the filtering) as CSV or iCalendar",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		eopts := exportOptions{}
+		subCmd.Flags().BoolVar(&eopts.csv, "csv", false, "Export the start, end, summary, location and link of the events as CSV")
+		subCmd.Flags().BoolVar(&eopts.ics, "ics", false, "Export the events as an iCalendar file (without the descriptions and the attendees)")
+		subCmd.Flags().StringVar(&eopts.file, "file", "", "File to write (default is the standard output)")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return export(authOptions(), opts, eopts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "agenda",
+			Short: "Print the events of the day in the terminal, with their durations and the current time",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
 		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
-			return run(getConfigDir(*configDir), *calendar)
+			return agenda(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "week",
+			Short: "Print the events of the current week, grouped by weekday",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return week(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "next",
+			Short: "Print the start time and the summary of the next event in one plain line (nothing when free)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return next(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "count",
+			Short: "Print the number of the remaining events of the day",
+			Long: "Print the number of the remaining events of the day.\n\n" +
+				"Exit codes: 0 if a meeting is in progress or imminent, 1 if free, 2 on authentication error, " +
+				"3 on network error, 4 on other errors.",
+			SilenceErrors: true,
+			SilenceUsage:  true,
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		// invalid flags and config files shouldn't exit with 1, it means free
+		subCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+			return &exitError{code: exitOtherError, err: err}
+		})
+		subCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+			if err := cmd.PersistentPreRunE(c, args); err != nil {
+				return &exitError{code: exitOtherError, err: err}
+			}
+			return nil
+		}
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return count(authOptions(), opts)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "stats",
+			Short: "Print the number and the length of the meetings of today and this week",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		perCalendar := subCmd.Flags().Bool("per-calendar", false, "Print the meeting load of each calendar, too")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return stats(authOptions(), opts, *perCalendar)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "cache-warm",
+			Short: "Refresh the token and fill the event cache without printing anything (eg. at the start of the session)",
+		}
+		opts := runOptions{}
+		addRunFlags(&subCmd, &opts)
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return cacheWarm(authOptions(), opts)
 		}
 		cmd.AddCommand(&subCmd)
 	}
@@ -39,8 +252,31 @@ func main() {
 			Use:   "setup",
 			Short: "Setup credentials",
 		}
+		warn := subCmd.Flags().Bool("warn-if-no-refresh-token", true, "Print a warning if the saved token can't be refreshed")
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return setup(authOptions(), *warn)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "setup-outlook",
+			Short: "Authorize the access to an [[outlook]] calendar of the config file",
+		}
+		name := subCmd.Flags().String("name", "", "Name of the outlook calendar in the config file")
+		deviceFlow := subCmd.Flags().Bool("device-flow", false, "Authorize with a code entered on another device")
 		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
-			return setup(getConfigDir(*configDir))
+			return setupOutlook(authOptions(), *name, *deviceFlow)
+		}
+		cmd.AddCommand(&subCmd)
+	}
+	{
+		subCmd := cobra.Command{
+			Use:   "doctor",
+			Short: "Check the saved credentials and token",
+		}
+		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return doctor(authOptions())
 		}
 		cmd.AddCommand(&subCmd)
 	}
@@ -49,17 +285,142 @@ func main() {
 			Use:   "list",
 			Short: "List available calendars",
 		}
+		jsonOutput := subCmd.Flags().Bool("json", false, "Print the calendars as a JSON array")
 		subCmd.RunE = func(cmd *cobra.Command, args []string) error {
-			return list(getConfigDir(*configDir))
+			return list(authOptions(), *jsonOutput)
 		}
 		cmd.AddCommand(&subCmd)
 	}
+	cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		if *profile != "" {
+			// each profile has its own credentials, token, config file and cache
+			*configDir = path.Join(*configDir, "profiles", *profile)
+		}
+		if err := applyConfigFile(c, effectiveConfigFile()); err != nil {
+			return err
+		}
+		if flag := c.Flags().Lookup("cache-dir"); flag != nil && !flag.Changed && *profile != "" {
+			dir, err := cacheDir("")
+			if err != nil {
+				return err
+			}
+			if err := c.Flags().Set("cache-dir", filepath.Join(dir, "profiles", *profile)); err != nil {
+				return errs.Wrap(err)
+			}
+		}
+		if *timezone != "" {
+			loc, err := time.LoadLocation(*timezone)
+			if err != nil {
+				return errs.Errorf("invalid timezone %q: %v", *timezone, err)
+			}
+			// all the times are rendered (and the days are computed) in the local timezone
+			time.Local = loc
+		}
+		return nil
+	}
 	err := cmd.Execute()
+	var exit *exitError
+	if errors.As(err, &exit) {
+		if exit.err != nil {
+			log.Printf("%++v", exit.err)
+		}
+		os.Exit(exit.code)
+	}
 	if err != nil {
 		log.Fatalf("%++v", err)
 	}
 }
 
+// addRunFlags registers the flags which control the fetching and rendering of the events.
+func addRunFlags(cmd *cobra.Command, opts *runOptions) {
+	flags := cmd.Flags()
+	flags.StringSliceVar(&opts.calendars, "calendar", []string{"primary"}, "Identifier of the calendar, can be repeated or comma separated (use list to print out available options")
+	flags.BoolVar(&opts.Pango, "pango", false, "Use pango markup in the tooltip")
+	flags.BoolVar(&opts.DeclinedInTooltipOnly, "include-declined-in-tooltip-only", false, "Never select declined events as next, but keep them (marked) in the tooltip")
+	flags.StringVar(&opts.EmptyIcon, "headline-empty-icon", "", "Text to show (with idle class) when there is no upcoming event")
+	flags.StringVar(&opts.TextTemplate, "text-template", "", "Go template used to render the bar text of the next event (fields: .Summary, .Start, .End, .Location, .Attendees, .Countdown, .VideoLink, .Extended, .ConferenceID, .DialIn, .PIN)")
+	flags.StringVar(&opts.TooltipTemplate, "tooltip-template", "", "Go template used to render one tooltip line per event")
+	flags.BoolVar(&opts.FetchExtended, "fetch-extended-properties", false, "Expose all private/shared extended properties to the templates as .Extended")
+	flags.StringSliceVar(&opts.ExtendedKeys, "extended-property-key", nil, "Extended property key to expose to the templates as .Extended.<key> (can be repeated)")
+	flags.BoolVar(&opts.DeclinedByOthers, "skip-declined-by-others", false, "Mark the next event with attendees-declined class when too many attendees declined it")
+	flags.Float64Var(&opts.DeclinedByOthersRatio, "attendees-declined-threshold", 0.5, "Ratio of the other attendees who should decline to mark the event (0 means any)")
+	flags.BoolVar(&opts.AllDayBanner, "render-all-day-banner", false, "Show all-day events as a banner in front of the next timed event")
+	flags.StringVar(&opts.AllDayBannerSeparator, "all-day-banner-separator", " | ", "Separator between the all-day banners and the next event")
+	flags.StringVar(&opts.Icon, "icon", "", "Icon to show in front of the next event")
+	flags.StringVar(&opts.HeadlineSeparator, "headline-sep", " ", "Separator between the time and the summary of the next event")
+	flags.StringVar(&opts.IconSeparator, "icon-sep", " ", "Separator between the icon and the next event")
+	flags.BoolVar(&opts.collapseRecurring, "collapse-recurring-in-selection", false, "Prefer the modified instance when both the series and the exception occurrence of a recurring event are returned")
+	flags.BoolVar(&opts.Heatmap, "show-heatmap", false, "Show the busy ratio of each hour as a sparkline in the tooltip")
+	flags.BoolVar(&opts.OutOfOffice, "respect-out-of-office-events", false, "Never select out-of-office events as next, use ooo class during them instead")
+	flags.StringVar(&opts.OutOfOfficeBanner, "ooo-banner", "", "Text to show in front of the next event during out-of-office events")
+	flags.BoolVar(&opts.PrefixDate, "headline-prefix-date-when-not-today", true, "Show the day (tmrw, weekday) in front of the next event if it's not today")
+	flags.IntVar(&opts.DimPastOpacity, "dim-past-events-opacity", 0, "Opacity (in percent) of the already finished events in the pango tooltip (0 means no dimming)")
+	flags.DurationVar(&opts.calendarTimeout, "fetch-timeout-per-calendar", 0, "Skip the calendar if it can't be fetched in time (0 means no timeout)")
+	flags.BoolVar(&opts.ShowRoom, "headline-show-room-if-physical", false, "Show the room of the next event if it's an in-person meeting")
+	flags.StringVar(&opts.RoomIcon, "room-icon", "🚪", "Icon of the in-person meetings (used with --headline-show-room-if-physical)")
+	flags.StringVar(&opts.VideoIcon, "video-icon", "📹", "Icon of the video meetings (used with --headline-show-room-if-physical)")
+	flags.BoolVar(&opts.persistMetrics, "persist-metrics", false, "Save the number and length of the meetings of the day to metrics.csv in the cache directory")
+	flags.BoolVar(&opts.TwoLine, "headline-two-line", false, "Show the time and the summary of the next event in two separate lines")
+	flags.DurationVar(&opts.AdvanceBefore, "ignore-events-before-now-by", 0, "Advance to the following event this long before the end of the current one")
+	flags.BoolVar(&opts.SuppressAllDayOnly, "suppress-all-day-only-days", false, "Show the idle state (instead of banners) when there are only all-day events")
+	flags.StringVar(&opts.AllDayOnlyText, "all-day-only-text", "", "Text to show on days with only all-day events (default is the --headline-empty-icon)")
+	flags.BoolVar(&opts.ShowLeftover, "headline-when-empty-show-yesterday-leftover", false, "Show the event started yesterday (and still in progress) if there is no other next event")
+	flags.StringVar(&opts.prometheusFile, "export-prometheus", "", "Write the meeting metrics to this file for the textfile collector of the node exporter")
+	flags.BoolVar(&opts.RelativeAndAbsolute, "headline-relative-and-absolute", false, "Show both the countdown and the start time of the next event (in 12m (10:00))")
+	flags.BoolVar(&opts.AbsoluteFirst, "absolute-first", false, "Show the start time before the countdown with --headline-relative-and-absolute (10:00 (in 12m))")
+	flags.StringSliceVar(&opts.onlyOnOutputs, "only-on-output", nil, "Render the events only on these outputs (empty item is printed on the other ones)")
+	flags.StringVar(&opts.output, "output", os.Getenv("WAYBAR_OUTPUT_NAME"), "Name of the current output (set by waybar as WAYBAR_OUTPUT_NAME)")
+	flags.BoolVar(&opts.MeetingNumber, "headline-include-meeting-number", false, "Show the conference id and the dial-in number (with the PIN) of the events in the tooltip")
+	flags.BoolVar(&opts.tomorrowFooter, "fetch-next-even-if-today-full", false, "Show the first event of tomorrow at the end of the tooltip")
+	flags.BoolVar(&opts.tooltipWeek, "tooltip-week", false, "Show the events of the week in the tooltip, grouped by weekday")
+	flags.StringVar(&opts.weekStart, "week-start", "monday", "First day of the week in the week overview")
+	flags.BoolVar(&opts.ignoreWeekends, "ignore-weekends-in-week-summary", false, "Omit Saturday and Sunday from the week overview")
+	flags.BoolVar(&opts.DropNoAttendeesTooltip, "drop-events-without-attendees-from-tooltip", false, "Hide the events without attendees from the tooltip")
+	flags.BoolVar(&opts.DropNoAttendeesHeadline, "drop-events-without-attendees-from-headline", false, "Never select the events without attendees as next event")
+	flags.DurationVar(&opts.MaxAgeOngoing, "headline-max-age-ongoing", 0, "Keep showing the event in progress as next for this long after its start (instead of the --grace-period), then advance to the following one")
+	flags.BoolVar(&opts.TimeRange, "tooltip-time-range", false, "Show the start and the end time of the events in the tooltip")
+	flags.BoolVar(&opts.OrganizerInitial, "headline-show-organizer-avatar-initial", false, "Show the initial of the organizer in front of the next event ([D] 10:00 Review)")
+	flags.StringSliceVar(&opts.sharedPropertyFilter, "extended-property-filter", nil, "Show only the events with this shared extended property (key=value, can be repeated)")
+	flags.StringSliceVar(&opts.privatePropertyFilter, "private-extended-property-filter", nil, "Show only the events with this private extended property (key=value, can be repeated)")
+	flags.DurationVar(&opts.CountdownUnder, "headline-show-countdown-only-under", 0, "Show the countdown instead of the start time if the next event starts sooner than this")
+	flags.BoolVar(&opts.UntilEnd, "headline-show-minutes-until-end-when-ongoing", false, "Show the time until the end instead of the start time if the next event is in progress (ends in 8m)")
+	flags.BoolVar(&opts.dedupeByICalUID, "dedupe-across-merge-by-ical-uid", false, "Show the same event (with the same iCalUID) only once")
+	flags.BoolVar(&opts.ConfirmedOnly, "headline-confirmed-only", false, "Select only confirmed (not tentative) events as next event")
+	flags.BoolVar(&opts.Progress, "headline-progress", false, "Keep showing the running meeting, with a progress bar instead of the start time (▓▓▓░░ Standup)")
+	flags.IntVar(&opts.ProgressWidth, "progress-width", 5, "Number of characters of the progress bar")
+	flags.DurationVar(&opts.cacheTTL, "cache-ttl", 0, "Use the cached events if they are fetched within this duration (0 means always fetch)")
+	flags.BoolVar(&opts.offlineFallback, "offline-fallback", true, "Show the cached events (with stale class) if the calendar can't be fetched")
+	flags.BoolVar(&opts.incrementalSync, "incremental-sync", false, "Download only the changed events since the previous run (using sync tokens saved to the cache directory)")
+	flags.DurationVar(&opts.Imminent, "imminent", 5*time.Minute, "Mark the next event as imminent (and urgent) if it starts sooner than this")
+	flags.BoolVar(&opts.includeAllDay, "include-all-day", true, "Include all-day events (use --include-all-day=false to hide them)")
+	flags.StringSliceVar(&opts.SkipResponseStatus, "skip-response-status", []string{"declined"}, "Hide events with these responses of mine (declined, needsAction, tentative, accepted)")
+	flags.DurationVar(&opts.GracePeriod, "grace-period", 5*time.Minute, "Keep showing an event as next for this long after its start")
+	flags.DurationVar(&opts.lookahead, "lookahead", 0, "Show the events this long after midnight too (eg. 10h for the next morning)")
+	flags.DurationVar(&opts.window, "window", 0, "Show the events of the next hours (eg. 12h) instead of the current day")
+	flags.BoolVar(&opts.ShowCurrent, "show-current", false, "Show the running meeting with its end time (\"Review until 11:30\") instead of the next one")
+	flags.BoolVar(&opts.ShowFree, "show-free", false, "Show how long you are free before the next meeting (\"free 1h45m\") when nothing is running")
+	flags.BoolVar(&opts.Countdown, "countdown", false, "Show the next event as a countdown after the summary (\"Standup in 12m\") instead of the start time")
+	flags.StringVar(&opts.TimeFormat, "time-format", "15:04", "Format of the times, Go layout (15:04) or strftime (%H:%M)")
+	flags.BoolVar(&opts.TwelveHour, "12h", false, "Use 12-hour clock (2:30 PM), shorthand of --time-format \"3:04 PM\"")
+	flags.BoolVar(&opts.ShowVideoLink, "tooltip-video-link", false, "Show the conference links (Meet, Zoom, Teams, Webex...) in the tooltip")
+	flags.StringVar(&opts.cacheDir, "cache-dir", "", "Directory of the cached events and metrics (default is waybar-google-calendar-check in the user cache dir)")
+	flags.StringSliceVar(&opts.accounts, "account", nil, "Merge the events of these accounts (set up with setup --profile <account>), instead of the default one")
+	flags.BoolVar(&opts.google, "google", true, "Fetch the Google calendars (use --google=false with only other providers)")
+	flags.StringArrayVar(&opts.icsURLs, "ics-url", nil, "iCalendar feed to merge with the other calendars (can be repeated)")
+	flags.DurationVar(&opts.icsRefresh, "ics-refresh", 15*time.Minute, "Download the iCalendar feeds at most this often")
+	flags.StringArrayVar(&opts.vdirs, "vdir", nil, "Local directory of .ics files (eg. synchronized by vdirsyncer) to merge with the other calendars (can be repeated)")
+	flags.StringArrayVar(&opts.plugins, "plugin", nil, "Command which prints the events as JSON, to merge with the other calendars (can be repeated)")
+	flags.StringVar(&opts.format, "format", "waybar", "Output format: waybar (JSON), polybar (one line with formatting tags), i3blocks (full text, short text and color lines), xmobar (markup), tmux (status line), plain (text only), xbar (xbar/SwiftBar plugin), eww (JSON with the events) or yambar (script module tags)")
+	flags.StringSliceVar(&opts.colors, "color", nil, "Color of the text for a class, like in-meeting=#ff5555 (can be repeated, used by the non-waybar formats)")
+	flags.StringVar(&opts.clickCommand, "click-command", "", "Command to execute on left click (used by the polybar and xmobar formats)")
+	flags.IntVar(&opts.shortLength, "short-length", 20, "Maximum number of characters of the short text of i3blocks (0 means no limit)")
+	flags.StringVar(&opts.plainSeparator, "plain-separator", " ", "Separator of the lines of the text in the plain format (with --headline-two-line)")
+	flags.IntVar(&opts.maxWidth, "max-width", 0, "Maximum number of characters of the plain text (0 means no limit)")
+	flags.BoolVar(&opts.ShowGuests, "show-guests-list", false, "Show the names of the attendees in the tooltip")
+	flags.IntVar(&opts.MaxGuests, "max-guests", 5, "Maximum number of attendee names to show with --show-guests-list")
+	flags.IntVar(&opts.MaxTitleWords, "max-title-words", 0, "Maximum number of words of the summary of the next event (0 means unlimited)")
+}
+
 func getConfigDir(dir string) string {
 	user, err := user.Current()
 	if err != nil {
@@ -68,43 +429,52 @@ func getConfigDir(dir string) string {
 	return strings.ReplaceAll(dir, "${HOME}", user.HomeDir)
 }
 
-func setup(configDir string) (err error) {
-	config, err := readCredentials(configDir)
+func setup(authOpts auth.Options, warn bool) (err error) {
+	config, err := auth.ReadCredentials(authOpts)
 	if err != nil {
 		return errs.Wrap(err)
 	}
 
 	ctx := context.Background()
-	token, _ := readToken(configDir)
+	token, _ := auth.ReadToken(authOpts)
 
 	token.Expiry = time.Now().Add(-time.Hour)
 
+	// the refreshed token keeps the scopes of the original consent, new scopes need a new consent
+	granted := calendar.CalendarReadonlyScope
+	if content, err := ioutil.ReadFile(path.Join(authOpts.ConfigDir, "scopes")); err == nil {
+		granted = string(content)
+	}
+	missing := auth.MissingScopes(config.Scopes, granted)
+	if token.RefreshToken != "" && len(missing) > 0 {
+		fmt.Println("Scopes are not granted yet, authorizing again:", strings.Join(missing, " "))
+	}
+
 	if !token.Valid() {
-		if token.RefreshToken != "" {
+		if token.RefreshToken != "" && len(missing) == 0 {
 			token, err = config.TokenSource(ctx, token).Token()
 			if err != nil {
 				fmt.Println(err)
 			}
 		}
 		if !token.Valid() {
-			fmt.Println(config.AuthCodeURL("no-state", oauth2.AccessTypeOffline))
-			var authCode string
-			if _, err := fmt.Scan(&authCode); err != nil {
-				return errs.Wrap(err)
+			token, err := auth.Authorize(ctx, config, false, "")
+			if err != nil {
+				return err
 			}
-			token, err := config.Exchange(ctx, authCode)
+			err = auth.WriteToken(authOpts, token)
 			if err != nil {
-				if _, err := fmt.Scan(&authCode); err != nil {
+				return err
+			}
+			if granted, ok := auth.GrantedScopes(token); ok {
+				fmt.Println("Granted scopes:", granted)
+				err = ioutil.WriteFile(path.Join(authOpts.ConfigDir, "scopes"), []byte(granted+"\n"), 0600)
+				if err != nil {
 					return errs.Wrap(err)
 				}
 			}
-			tokenBytes, err := json.Marshal(token)
-			if err != nil {
-				return errs.Wrap(err)
-			}
-			err = ioutil.WriteFile(path.Join(configDir, "token.json"), tokenBytes, 0600)
-			if err != nil {
-				return errs.Wrap(err)
+			if warn {
+				warnIfNoRefreshToken(os.Stderr, token)
 			}
 		}
 
@@ -113,115 +483,341 @@ func setup(configDir string) (err error) {
 
 }
 
-func list(configDir string) error {
-	ctx := context.Background()
+// warnIfNoRefreshToken warns if the token will stop working after the access token is expired.
+func warnIfNoRefreshToken(w io.Writer, token *oauth2.Token) bool {
+	if token != nil && token.RefreshToken != "" {
+		return false
+	}
+	_, _ = fmt.Fprintln(w, "WARNING: the saved token has no refresh token, it will stop working when the access token expires (in about an hour).")
+	_, _ = fmt.Fprintln(w, "Revoke the access of the application at https://myaccount.google.com/permissions and run setup again to give consent.")
+	return true
+}
 
-	config, err := readCredentials(configDir)
+func doctor(authOpts auth.Options) error {
+	config, err := auth.ReadCredentials(authOpts)
 	if err != nil {
-		return errs.Wrap(err)
+		return err
 	}
-	token, err := readToken(configDir)
+	fmt.Println("credentials: OK")
+
+	token, err := auth.ReadToken(authOpts)
 	if err != nil {
 		return err
 	}
+	fmt.Println("token: OK")
 
-	service, err := calendar.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx, token)))
+	if warnIfNoRefreshToken(os.Stdout, token) {
+		return nil
+	}
+	fmt.Println("refresh token: OK")
+
+	token.Expiry = time.Now().Add(-time.Hour)
+	_, err = config.TokenSource(context.Background(), token).Token()
 	if err != nil {
-		return errs.Wrap(err)
+		return errs.Errorf("token couldn't be refreshed: %v", err)
+	}
+	fmt.Println("token refresh: OK")
+	return nil
+}
+
+// CalendarInfo is the JSON representation of one calendar in the output of list --json.
+type CalendarInfo struct {
+	ID              string `json:"id"`
+	Summary         string `json:"summary"`
+	Description     string `json:"description"`
+	AccessRole      string `json:"accessRole"`
+	Primary         bool   `json:"primary"`
+	BackgroundColor string `json:"backgroundColor"`
+}
+
+func list(authOpts auth.Options, jsonOutput bool) error {
+	ctx := context.Background()
+
+	service, err := newService(ctx, authOpts)
+	if err != nil {
+		return err
 	}
 	calendars, err := service.CalendarList.List().Do()
 	if err != nil {
 		return errs.Wrap(err)
 	}
-	for _, cal := range calendars.Items {
-		fmt.Printf("%s %s\n", cal.Id, cal.Description)
+	return printCalendars(os.Stdout, calendars.Items, jsonOutput)
+}
+
+// printCalendars prints the id and the description of the calendars, or a JSON array of CalendarInfo.
+func printCalendars(w io.Writer, calendars []*calendar.CalendarListEntry, jsonOutput bool) error {
+	if jsonOutput {
+		infos := []CalendarInfo{}
+		for _, cal := range calendars {
+			infos = append(infos, CalendarInfo{
+				ID:              cal.Id,
+				Summary:         cal.Summary,
+				Description:     cal.Description,
+				AccessRole:      cal.AccessRole,
+				Primary:         cal.Primary,
+				BackgroundColor: cal.BackgroundColor,
+			})
+		}
+		return errs.Wrap(json.NewEncoder(w).Encode(infos))
+	}
+	for _, cal := range calendars {
+		if _, err := fmt.Fprintf(w, "%s %s\n", cal.Id, cal.Description); err != nil {
+			return errs.Wrap(err)
+		}
 	}
 	return nil
 }
 
-type Event struct {
-	start time.Time
-	raw   *calendar.Event
+// runOptions are the options of fetching and rendering the events.
+type runOptions struct {
+	render.Options
+	calendars             []string
+	collapseRecurring     bool
+	calendarTimeout       time.Duration
+	persistMetrics        bool
+	prometheusFile        string
+	onlyOnOutputs         []string
+	output                string
+	tomorrowFooter        bool
+	tooltipWeek           bool
+	weekStart             string
+	ignoreWeekends        bool
+	sharedPropertyFilter  []string
+	privatePropertyFilter []string
+	dedupeByICalUID       bool
+	cacheTTL              time.Duration
+	offlineFallback       bool
+	incrementalSync       bool
+	includeAllDay         bool
+	lookahead             time.Duration
+	window                time.Duration
+	cacheDir              string
+	accounts              []string
+	google                bool
+	icsURLs               []string
+	icsRefresh            time.Duration
+	vdirs                 []string
+	plugins               []string
+	format                string
+	colors                []string
+	clickCommand          string
+	shortLength           int
+	plainSeparator        string
+	maxWidth              int
 }
 
-func run(configDir string, id string) (err error) {
+func run(authOpts auth.Options, opts runOptions) (err error) {
 	ctx := context.Background()
 
-	config, err := readCredentials(configDir)
+	calendars, err := newSources(ctx, authOpts, opts)
 	if err != nil {
-		return errs.Wrap(err)
+		return err
 	}
-	token, err := readToken(configDir)
+
+	now := time.Now()
+	item, events, err := refresh(ctx, calendars, now, opts)
+	if err != nil {
+		// waybar hides the module on failure, the error is shown with the error class instead
+		_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+		item = render.ErrorItem(err)
+	}
+	line, err := formatItem(item, events, now, opts)
 	if err != nil {
 		return err
 	}
+	fmt.Println(line)
+	return nil
+}
 
-	service, err := calendar.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx, token)))
+// refresh fetches the events and renders the waybar item. Returns the fetched events, too.
+func refresh(ctx context.Context, calendars []providers.Source, now time.Time, opts runOptions) (render.BarItem, []render.Event, error) {
+	if !visibleOnOutput(opts) {
+		return render.BarItem{}, nil, nil
+	}
+	events, err := fetch(ctx, calendars, now, opts)
 	if err != nil {
-		return errs.Wrap(err)
+		return render.BarItem{}, nil, err
+	}
+	if opts.persistMetrics {
+		dir, err := cacheDir(opts.cacheDir)
+		if err != nil {
+			return render.BarItem{}, nil, err
+		}
+		if err := persistMetrics(filepath.Join(dir, "metrics.csv"), now, events, opts.Options); err != nil {
+			return render.BarItem{}, nil, err
+		}
+	}
+	if opts.prometheusFile != "" {
+		if err := osutil.WriteFileAtomic(opts.prometheusFile, []byte(prometheusMetrics(events, now, opts.Options)), 0644); err != nil {
+			return render.BarItem{}, nil, err
+		}
+	}
+	item, err := render.Render(events, now, opts.Options)
+	if err != nil {
+		return render.BarItem{}, nil, err
+	}
+	if opts.tooltipWeek {
+		item.Tooltip, err = weekOverview(ctx, calendars, now, opts)
+		if err != nil {
+			return render.BarItem{}, nil, err
+		}
 	}
+	for _, event := range events {
+		if event.Stale {
+			item.Class = append(item.Class, "stale")
+			item.Tooltip = "(offline, cached events)\n" + item.Tooltip
+			break
+		}
+	}
+	if opts.tomorrowFooter {
+		footer, err := tomorrowFooter(ctx, calendars, now, opts)
+		if err != nil {
+			return render.BarItem{}, nil, err
+		}
+		item.Tooltip += footer
+	}
+	return item, events, nil
+}
 
-	from := time.Now().Truncate(time.Hour * 24)
-	to := from.Add(time.Hour * 24)
-	events, err := service.Events.List(id).TimeMin(from.Format(time.RFC3339)).SingleEvents(true).TimeMax(to.Format(time.RFC3339)).Do()
+// tomorrowFooter returns the tooltip line with the first timed event of the next day.
+func tomorrowFooter(ctx context.Context, calendars []providers.Source, now time.Time, opts runOptions) (string, error) {
+	from := render.StartOfDay(now).AddDate(0, 0, 1)
+	events, err := fetchRange(ctx, calendars, from, from.AddDate(0, 0, 1), opts)
 	if err != nil {
-		return errs.Wrap(err)
+		return "", err
 	}
+	for _, event := range events {
+		if event.AllDay || event.Start.Before(from) {
+			continue
+		}
+		return fmt.Sprintf("Tomorrow: %s %s\n", render.FormatClock(event.Start, opts.Options), render.TooltipText(event.Raw.Summary, opts.Options)), nil
+	}
+	return "", nil
+}
 
-	sort.Slice(events.Items, func(i, j int) bool {
-		start1, _ := time.Parse(time.RFC3339, events.Items[i].Start.DateTime)
-		start2, _ := time.Parse(time.RFC3339, events.Items[j].Start.DateTime)
-		return start1.Before(start2)
-	})
+// visibleOnOutput returns false if the module is restricted to other outputs than the current one.
+func visibleOnOutput(opts runOptions) bool {
+	return len(opts.onlyOnOutputs) == 0 || contains(opts.onlyOnOutputs, opts.output)
+}
 
-	jsonOutput := json.NewEncoder(os.Stdout)
-	if len(events.Items) == 0 {
-		return jsonOutput.Encode(BarItem{
-			Text: "",
-		})
-	}
+// fetch returns the (sorted) events of the day.
+func fetch(ctx context.Context, calendars []providers.Source, now time.Time, opts runOptions) ([]render.Event, error) {
+	from, to := queryWindow(now, opts)
+	return fetchRange(ctx, calendars, from, to, opts)
+}
 
-	var next *calendar.Event
-	alt := ""
-	for i := 0; i < len(events.Items); i++ {
-		start, _ := time.Parse(time.RFC3339, events.Items[i].Start.DateTime)
-		if next == nil && time.Now().Before(start.Add(5*time.Minute)) {
-			next = events.Items[i]
-		}
-		alt += fmt.Sprintf("%s %s\n", start.Format("15:04"), events.Items[i].Summary)
+// queryWindow returns the time range of the events to show: the current day, extended with the lookahead, or the
+// next hours in rolling window mode.
+func queryWindow(now time.Time, opts runOptions) (time.Time, time.Time) {
+	if opts.window > 0 {
+		from := now.Truncate(time.Minute)
+		return from, from.Add(opts.window)
+	}
+	from := render.StartOfDay(now)
+	return from, from.AddDate(0, 0, 1).Add(opts.lookahead)
+}
 
+// fetchRange returns the (sorted) events between from and to.
+func fetchRange(ctx context.Context, calendars []providers.Source, from time.Time, to time.Time, opts runOptions) ([]render.Event, error) {
+	results := make([][]*calendar.Event, len(calendars))
+	stale := make([]bool, len(calendars))
+	failures := make([]error, len(calendars))
+	var wg sync.WaitGroup
+	for i, cal := range calendars {
+		wg.Add(1)
+		go func(i int, cal providers.Source) {
+			defer wg.Done()
+			results[i], stale[i], failures[i] = fetchCalendar(ctx, cal, from, to, opts)
+		}(i, cal)
 	}
+	wg.Wait()
 
-	start, _ := time.Parse(time.RFC3339, next.Start.DateTime)
-	return jsonOutput.Encode(BarItem{
-		Text:    fmt.Sprintf("%s %s", start.Format("15:04"), next.Summary),
-		Tooltip: alt,
+	var items []render.Event
+	for i, events := range results {
+		if failures[i] != nil {
+			return nil, failures[i]
+		}
+		for _, raw := range events {
+			event := render.NewEvent(raw)
+			// cancelled occurrences of recurring events can be returned even without ShowDeleted
+			if raw.Status == "cancelled" {
+				continue
+			}
+			if event.AllDay && !opts.includeAllDay {
+				continue
+			}
+			if render.Skipped(event, opts.Options) {
+				continue
+			}
+			event.Calendar = calendars[i].Name()
+			event.Stale = stale[i]
+			items = append(items, event)
+		}
+	}
+	if opts.collapseRecurring {
+		items = render.CollapseRecurring(items)
+	}
+	// the same invitation can be received by more accounts
+	if opts.dedupeByICalUID || len(opts.accounts) > 1 {
+		items = render.DedupeByICalUID(items)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Start.Before(items[j].Start)
 	})
+	return items, nil
 }
-9
-func readToken(dir string) (*oauth2.Token, error) {
-	t := &oauth2.Token{}
-	content, err := ioutil.ReadFile(path.Join(dir, "token.json"))
-	if err != nil {
-		return t, errs.Wrap(err)
+
+// fetchCalendar returns the raw events of one calendar, using the on-disk cache if configured.
+// Returns stale=true if the events are served from an outdated cache because the API is not available.
+func fetchCalendar(ctx context.Context, cal providers.Source, from time.Time, to time.Time, opts runOptions) (events []*calendar.Event, stale bool, err error) {
+	var dir string
+	if opts.cacheTTL > 0 || opts.offlineFallback {
+		dir, err = cacheDir(opts.cacheDir)
+		if err != nil {
+			return nil, false, err
+		}
 	}
-	err = json.Unmarshal(content, t)
-	if err != nil {
-		return t, errs.Wrap(err)
+	file := eventCacheFile(dir, cal.Name(), from, to)
+
+	if opts.cacheTTL > 0 {
+		if cached := readEventCache(file); cached != nil && time.Since(cached.Fetched) < opts.cacheTTL {
+			return cached.Events, false, nil
+		}
 	}
-	return t, nil
-}
 
-func readCredentials(configDir string) (*oauth2.Config, error) {
-	credentialFile := path.Join(configDir, "credentials.json")
-	content, err := ioutil.ReadFile(credentialFile)
+	queryCtx := ctx
+	if opts.calendarTimeout > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, opts.calendarTimeout)
+		defer cancel()
+	}
+	events, err = cal.Events(queryCtx, from, to)
 	if err != nil {
-		return nil, errs.Errorf("Couldn't read credentials file from %s: %v", credentialFile, err)
+		if opts.offlineFallback {
+			if cached := readEventCache(file); cached != nil {
+				log.Printf("using cached events of %s (fetched at %s): %v", cal.Name(), cached.Fetched.Format(time.RFC3339), err)
+				return cached.Events, true, nil
+			}
+		}
+		if opts.calendarTimeout > 0 && queryCtx.Err() == context.DeadlineExceeded {
+			log.Printf("calendar %s is skipped, it's not fetched in %s", cal.Name(), opts.calendarTimeout)
+			return nil, false, nil
+		}
+		return nil, false, err
 	}
 
-	config, err := google.ConfigFromJSON(content, calendar.CalendarReadonlyScope)
-	if err != nil {
-		log.Fatalf("Couldn't parse configuration: %v", err)
+	if dir != "" {
+		err = osutil.WriteJSON(file, eventCache{
+			Fetched:  time.Now(),
+			Calendar: cal.Name(),
+			From:     from,
+			To:       to,
+			Events:   events,
+		})
+		if err != nil {
+			log.Printf("events couldn't be cached: %v", err)
+		}
+		pruneEventCache(dir, cal.Name())
 	}
-	return config, nil
+	return events, false, nil
 }
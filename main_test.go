@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource is a calendar with fixed events. The events starting in the queried range are returned after the delay (or
+// the cancellation of the context).
+type fakeSource struct {
+	name   string
+	delay  time.Duration
+	events []*calendar.Event
+}
+
+func (f fakeSource) Name() string {
+	return f.name
+}
+
+func (f fakeSource) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	select {
+	case <-time.After(f.delay):
+		var res []*calendar.Event
+		for _, event := range f.events {
+			if start := render.NewEvent(event).Start; !start.Before(from) && start.Before(to) {
+				res = append(res, event)
+			}
+		}
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func fakeEvent(summary string, start time.Time, end time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:      strings.ToLower(summary),
+		Summary: summary,
+		Status:  "confirmed",
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+	}
+}
+
+func summaries(events []render.Event) string {
+	var res []string
+	for _, event := range events {
+		res = append(res, event.Raw.Summary)
+	}
+	return strings.Join(res, ",")
+}
+
+func TestWarnIfNoRefreshToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token *oauth2.Token
+		warn  bool
+	}{
+		{name: "refresh token", token: &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}},
+		{name: "no refresh token", token: &oauth2.Token{AccessToken: "access"}, warn: true},
+		{name: "no token", warn: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.Buffer{}
+			if warned := warnIfNoRefreshToken(&out, tc.token); warned != tc.warn {
+				t.Fatalf("expected %v, got %v", tc.warn, warned)
+			}
+			if strings.Contains(out.String(), "WARNING") != tc.warn {
+				t.Fatalf("unexpected output %q", out.String())
+			}
+		})
+	}
+}
+
+func TestFetchTimeoutPerCalendar(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	calendars := []providers.Source{
+		fakeSource{name: "work", events: []*calendar.Event{fakeEvent("Standup", day.Add(10*time.Hour), day.Add(11*time.Hour))}},
+		fakeSource{name: "slow", delay: time.Minute, events: []*calendar.Event{fakeEvent("Lunch", day.Add(12*time.Hour), day.Add(13*time.Hour))}},
+		fakeSource{name: "home", delay: 10 * time.Millisecond, events: []*calendar.Event{fakeEvent("Dentist", day.Add(16*time.Hour), day.Add(17*time.Hour))}},
+	}
+	opts := runOptions{calendarTimeout: 200 * time.Millisecond, includeAllDay: true}
+
+	started := time.Now()
+	events, err := fetchRange(context.Background(), calendars, day, day.AddDate(0, 0, 1), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("the slow calendar blocked the fetch for %s", elapsed)
+	}
+	if got := summaries(events); got != "Standup,Dentist" {
+		t.Fatalf("expected the events of the responsive calendars, got %s", got)
+	}
+}
+
+func TestPrintCalendars(t *testing.T) {
+	calendars := []*calendar.CalendarListEntry{
+		{Id: "me@example.com", Summary: "Me", Description: "personal", AccessRole: "owner", Primary: true, BackgroundColor: "#9fe1e7"},
+		{Id: "team@group.calendar.google.com", Summary: "Team", AccessRole: "reader"},
+	}
+	tests := []struct {
+		name       string
+		calendars  []*calendar.CalendarListEntry
+		jsonOutput bool
+		expected   string
+	}{
+		{
+			name:      "text",
+			calendars: calendars,
+			expected:  "me@example.com personal\nteam@group.calendar.google.com \n",
+		},
+		{
+			name:       "json",
+			calendars:  calendars,
+			jsonOutput: true,
+			expected: `[{"id":"me@example.com","summary":"Me","description":"personal","accessRole":"owner","primary":true,"backgroundColor":"#9fe1e7"},` +
+				`{"id":"team@group.calendar.google.com","summary":"Team","description":"","accessRole":"reader","primary":false,"backgroundColor":""}]` + "\n",
+		},
+		{name: "empty json", jsonOutput: true, expected: "[]\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.Buffer{}
+			if err := printCalendars(&out, tc.calendars, tc.jsonOutput); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestOnlyOnOutput(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	calendars := []providers.Source{
+		fakeSource{name: "work", events: []*calendar.Event{fakeEvent("Standup", day.Add(10*time.Hour), day.Add(11*time.Hour))}},
+	}
+	tests := []struct {
+		name     string
+		outputs  []string
+		output   string
+		expected string
+	}{
+		{name: "not restricted", output: "DP-1", expected: "10:00 Standup"},
+		{name: "gated on", outputs: []string{"eDP-1", "DP-1"}, output: "DP-1", expected: "10:00 Standup"},
+		{name: "gated off", outputs: []string{"eDP-1"}, output: "DP-1"},
+		{name: "unknown output", outputs: []string{"eDP-1"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := runOptions{
+				Options:       render.Options{TimeFormat: "15:04", HeadlineSeparator: " ", GracePeriod: 5 * time.Minute},
+				includeAllDay: true,
+				onlyOnOutputs: tc.outputs,
+				output:        tc.output,
+			}
+			item, events, err := refresh(context.Background(), calendars, day.Add(9*time.Hour), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, item.Text)
+			}
+			if tc.expected == "" && (item.Tooltip != "" || len(item.Class) > 0 || len(events) > 0) {
+				t.Fatalf("expected empty item on the other outputs, got %+v", item)
+			}
+		})
+	}
+}
+
+func TestTomorrowFooter(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	tomorrowAllDay := &calendar.Event{
+		Id:      "holiday",
+		Summary: "Holiday",
+		Status:  "confirmed",
+		Start:   &calendar.EventDateTime{Date: "2021-06-02"},
+		End:     &calendar.EventDateTime{Date: "2021-06-03"},
+	}
+	tests := []struct {
+		name     string
+		events   []*calendar.Event
+		expected string
+	}{
+		{
+			name: "first event of tomorrow",
+			events: []*calendar.Event{
+				fakeEvent("Review", day.Add(10*time.Hour), day.Add(11*time.Hour)),
+				tomorrowAllDay,
+				fakeEvent("Planning", day.Add(35*time.Hour), day.Add(36*time.Hour)),
+				fakeEvent("Standup", day.Add(33*time.Hour), day.Add(33*time.Hour+15*time.Minute)),
+			},
+			expected: "10:00 Review\nTomorrow: 09:00 Standup\n",
+		},
+		{
+			name:     "free tomorrow",
+			events:   []*calendar.Event{fakeEvent("Review", day.Add(10*time.Hour), day.Add(11*time.Hour)), tomorrowAllDay},
+			expected: "10:00 Review\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calendars := []providers.Source{fakeSource{name: "work", events: tc.events}}
+			opts := runOptions{
+				Options:        render.Options{TimeFormat: "15:04", HeadlineSeparator: " ", GracePeriod: 5 * time.Minute},
+				includeAllDay:  true,
+				tomorrowFooter: true,
+			}
+			// only today is shown, the footer is independent of the queried days
+			item, _, err := refresh(context.Background(), calendars, day.Add(9*time.Hour), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Tooltip != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, item.Tooltip)
+			}
+		})
+	}
+}
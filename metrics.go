@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheDir returns the directory of the cached/generated files (and creates it if it's missing). Empty dir means the
+// default directory in the user cache dir.
+func cacheDir(dir string) (string, error) {
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", errs.Wrap(err)
+		}
+		dir = filepath.Join(userDir, auth.AppName)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errs.Wrap(err)
+	}
+	return dir, nil
+}
+
+// meetingLoad returns the number and the summarized length of the timed events.
+func meetingLoad(events []render.Event) (count int, length time.Duration) {
+	for _, event := range events {
+		if event.AllDay || event.End.Before(event.Start) {
+			continue
+		}
+		count++
+		length += event.End.Sub(event.Start)
+	}
+	return count, length
+}
+
+// dayMeetings returns the events of the day which could be shown in the headline, without the declined ones and the
+// events of the lookahead.
+func dayMeetings(events []render.Event, day time.Time, opts render.Options) []render.Event {
+	from := render.StartOfDay(day)
+	to := from.AddDate(0, 0, 1)
+	var res []render.Event
+	for _, event := range events {
+		if event.Start.Before(from) || !event.Start.Before(to) || event.Declined() || !render.HeadlineCandidate(event, opts) {
+			continue
+		}
+		res = append(res, event)
+	}
+	return res
+}
+
+// persistMetrics saves the meeting load of the day to the metrics.csv file. Earlier lines of the same day are replaced.
+func persistMetrics(file string, day time.Time, events []render.Event, opts render.Options) error {
+	date := day.Format("2006-01-02")
+	count, length := meetingLoad(dayMeetings(events, day, opts))
+
+	var lines []string
+	content, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return errs.Wrap(err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" || strings.HasPrefix(line, date+",") {
+			continue
+		}
+		if _, err := parseMetricsLine(line); err != nil {
+			log.Printf("dropping corrupt line from %s: %v", file, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, fmt.Sprintf("%s,%d,%d", date, count, int(length.Minutes())))
+	return osutil.WriteFileAtomic(file, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+// dailyMetrics is one line of the metrics.csv.
+type dailyMetrics struct {
+	day     time.Time
+	count   int
+	minutes int
+}
+
+func parseMetricsLine(line string) (dailyMetrics, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) != 3 {
+		return dailyMetrics{}, errs.Errorf("invalid metrics line %q", line)
+	}
+	day, err := time.ParseInLocation("2006-01-02", fields[0], time.Local)
+	if err != nil {
+		return dailyMetrics{}, errs.Errorf("invalid date in metrics line %q", line)
+	}
+	count, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return dailyMetrics{}, errs.Errorf("invalid count in metrics line %q", line)
+	}
+	minutes, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return dailyMetrics{}, errs.Errorf("invalid length in metrics line %q", line)
+	}
+	return dailyMetrics{day: day, count: count, minutes: minutes}, nil
+}
+
+// readMetrics returns the valid lines of the metrics.csv file (or nothing if it doesn't exist).
+func readMetrics(file string) ([]dailyMetrics, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	var res []dailyMetrics
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		metrics, err := parseMetricsLine(line)
+		if err != nil {
+			log.Printf("skipping corrupt line of %s: %v", file, err)
+			continue
+		}
+		res = append(res, metrics)
+	}
+	return res, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"google.golang.org/api/calendar/v3"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testEvent creates a timed event, times are RFC3339.
+func testEvent(summary string, start string, end string) render.Event {
+	return render.NewEvent(&calendar.Event{
+		Summary: summary,
+		Start:   &calendar.EventDateTime{DateTime: start},
+		End:     &calendar.EventDateTime{DateTime: end},
+	})
+}
+
+func declined(event render.Event) render.Event {
+	event.Raw.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "declined"}}
+	return event
+}
+
+func TestMeetingLoad(t *testing.T) {
+	allDay := render.NewEvent(&calendar.Event{
+		Start: &calendar.EventDateTime{Date: "2021-06-01"},
+		End:   &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	tests := []struct {
+		name   string
+		events []render.Event
+		count  int
+		length time.Duration
+	}{
+		{name: "empty"},
+		{
+			name: "timed events",
+			events: []render.Event{
+				testEvent("a", "2021-06-01T10:00:00Z", "2021-06-01T10:30:00Z"),
+				testEvent("b", "2021-06-01T11:00:00Z", "2021-06-01T12:00:00Z"),
+			},
+			count:  2,
+			length: 90 * time.Minute,
+		},
+		{
+			name:   "all-day events are not meetings",
+			events: []render.Event{allDay, testEvent("a", "2021-06-01T10:00:00Z", "2021-06-01T10:15:00Z")},
+			count:  1,
+			length: 15 * time.Minute,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			count, length := meetingLoad(tc.events)
+			if count != tc.count || length != tc.length {
+				t.Fatalf("expected %d/%s, got %d/%s", tc.count, tc.length, count, length)
+			}
+		})
+	}
+}
+
+func TestDayMeetings(t *testing.T) {
+	day := time.Date(2021, 6, 1, 9, 0, 0, 0, time.Local)
+	at := func(hour int) string {
+		return day.Add(time.Duration(hour-9) * time.Hour).Format(time.RFC3339)
+	}
+	events := []render.Event{
+		testEvent("today", at(10), at(11)),
+		declined(testEvent("declined", at(12), at(13))),
+		testEvent("lookahead", at(32), at(33)),
+	}
+	got := dayMeetings(events, day, render.Options{})
+	if len(got) != 1 || got[0].Raw.Summary != "today" {
+		t.Fatalf("only today's accepted meeting should be counted, got %v", got)
+	}
+}
+
+func TestPersistMetrics(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metrics.csv")
+	first := time.Date(2021, 6, 1, 9, 0, 0, 0, time.Local)
+	second := first.AddDate(0, 0, 1)
+	meeting := func(day time.Time, minutes int) render.Event {
+		start := day.Add(time.Hour)
+		return testEvent("m", start.Format(time.RFC3339), start.Add(time.Duration(minutes)*time.Minute).Format(time.RFC3339))
+	}
+
+	steps := []struct {
+		day      time.Time
+		events   []render.Event
+		expected string
+	}{
+		{
+			day:      first,
+			events:   []render.Event{meeting(first, 30)},
+			expected: "2021-06-01,1,30\n",
+		},
+		{
+			// the line of the same day is replaced
+			day:      first,
+			events:   []render.Event{meeting(first, 30), meeting(first, 45)},
+			expected: "2021-06-01,2,75\n",
+		},
+		{
+			day:      second,
+			events:   []render.Event{meeting(second, 60)},
+			expected: "2021-06-01,2,75\n2021-06-02,1,60\n",
+		},
+	}
+	for i, step := range steps {
+		if err := persistMetrics(file, step.day, step.events, render.Options{}); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != step.expected {
+			t.Fatalf("step %d: expected %q, got %q", i, step.expected, string(content))
+		}
+	}
+
+	history, err := readMetrics(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].count != 1 || history[1].minutes != 60 {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestPersistMetricsDropsCorruptLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metrics.csv")
+	if err := ioutil.WriteFile(file, []byte("2021-05-31,1,30\n2021-05-3"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2021, 6, 1, 9, 0, 0, 0, time.Local)
+	if err := persistMetrics(file, day, nil, render.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "2021-05-31,1,30\n2021-06-01,0,0\n"; string(content) != expected {
+		t.Fatalf("expected %q, got %q", expected, string(content))
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"time"
+)
+
+// next prints the next event as "<start> <summary>" (or nothing when there is no next event), for shell prompts and
+// scripts.
+func next(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	events, err := fetch(ctx, calendars, now, opts)
+	if err != nil {
+		return err
+	}
+	if line := nextLine(events, now, opts.Options); line != "" {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// nextLine returns the start time (or "all day") and the summary of the selected next event.
+func nextLine(events []render.Event, now time.Time, opts render.Options) string {
+	event := render.SelectNext(events, now, opts)
+	if event == nil {
+		return ""
+	}
+	if event.AllDay {
+		return "all day " + event.Raw.Summary
+	}
+	return render.FormatClock(event.Start, opts) + " " + event.Raw.Summary
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/godbus/dbus/v5"
+	"github.com/zeebo/errs/v2"
+	"log"
+	"os/exec"
+	"sync"
+)
+
+// notification is a desktop notification about an event.
+type notification struct {
+	// key identifies the event, notifications with the same key replace each other (if supported).
+	key   string
+	title string
+	body  string
+	// link is opened by the Join action (if supported).
+	link string
+}
+
+type notifier interface {
+	notify(n notification) error
+}
+
+func newNotifier(backend string) (notifier, error) {
+	switch backend {
+	case "", "notify-send":
+		return notifySend{}, nil
+	case "dbus":
+		conn, err := dbus.SessionBus()
+		if err != nil {
+			log.Printf("D-Bus session bus is not available, using notify-send: %v", err)
+			return notifySend{}, nil
+		}
+		n := newDBusNotifier(conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications"))
+		err = conn.AddMatchSignal(dbus.WithMatchInterface("org.freedesktop.Notifications"), dbus.WithMatchMember("ActionInvoked"))
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		signals := make(chan *dbus.Signal, 16)
+		conn.Signal(signals)
+		go n.handleSignals(signals, osutil.OpenLink)
+		return n, nil
+	default:
+		return nil, errs.Errorf("unknown notification backend %q (use notify-send or dbus)", backend)
+	}
+}
+
+// notifySend shows the notifications with the notify-send command.
+type notifySend struct{}
+
+func (notifySend) notify(n notification) error {
+	body := n.body
+	if n.link != "" {
+		body += "\n" + n.link
+	}
+	return errs.Wrap(exec.Command("notify-send", "--app-name", auth.AppName, n.title, body).Run())
+}
+
+// dbusNotifier calls the org.freedesktop.Notifications interface directly, with replace ids and Join action.
+type dbusNotifier struct {
+	bus dbus.BusObject
+
+	mu         sync.Mutex
+	replaceIDs map[string]uint32
+	links      map[uint32]string
+}
+
+func newDBusNotifier(bus dbus.BusObject) *dbusNotifier {
+	return &dbusNotifier{
+		bus:        bus,
+		replaceIDs: map[string]uint32{},
+		links:      map[uint32]string{},
+	}
+}
+
+func (d *dbusNotifier) notify(n notification) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	actions := []string{}
+	if n.link != "" {
+		actions = append(actions, "join", "Join")
+	}
+	var id uint32
+	err := d.bus.Call("org.freedesktop.Notifications.Notify", 0,
+		auth.AppName, d.replaceIDs[n.key], "", n.title, n.body, actions, map[string]dbus.Variant{}, int32(-1)).Store(&id)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	d.replaceIDs[n.key] = id
+	if n.link != "" {
+		d.links[id] = n.link
+	}
+	return nil
+}
+
+// handleSignals opens the link of the notification when the Join action is invoked.
+func (d *dbusNotifier) handleSignals(signals <-chan *dbus.Signal, open func(link string) error) {
+	for signal := range signals {
+		if signal.Name != "org.freedesktop.Notifications.ActionInvoked" || len(signal.Body) < 2 {
+			continue
+		}
+		id, _ := signal.Body[0].(uint32)
+		action, _ := signal.Body[1].(string)
+		if action != "join" {
+			continue
+		}
+		d.mu.Lock()
+		link := d.links[id]
+		d.mu.Unlock()
+		if link == "" {
+			continue
+		}
+		if err := open(link); err != nil {
+			log.Printf("couldn't open %s: %v", link, err)
+		}
+	}
+}
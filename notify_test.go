@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/godbus/dbus/v5"
+	"google.golang.org/api/calendar/v3"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeBus records the method calls of the notification daemon and returns increasing notification ids.
+type fakeBus struct {
+	dbus.BusObject
+	methods []string
+	args    [][]interface{}
+}
+
+func (f *fakeBus) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	f.methods = append(f.methods, method)
+	f.args = append(f.args, args)
+	return &dbus.Call{Body: []interface{}{uint32(len(f.args))}}
+}
+
+func TestDBusNotify(t *testing.T) {
+	bus := &fakeBus{}
+	n := newDBusNotifier(bus)
+	notifications := []notification{
+		{key: "review", title: "Review", body: "10:00 in 10m", link: "https://meet.google.com/abc-defg-hij"},
+		{key: "review", title: "Review", body: "10:00 in 5m", link: "https://meet.google.com/abc-defg-hij"},
+		{key: "lunch", title: "Lunch", body: "12:00 in 10m\nKitchen"},
+	}
+	for _, notification := range notifications {
+		if err := n.notify(notification); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := [][]interface{}{
+		{auth.AppName, uint32(0), "", "Review", "10:00 in 10m", []string{"join", "Join"}, map[string]dbus.Variant{}, int32(-1)},
+		// the second reminder of the same event replaces the first one
+		{auth.AppName, uint32(1), "", "Review", "10:00 in 5m", []string{"join", "Join"}, map[string]dbus.Variant{}, int32(-1)},
+		{auth.AppName, uint32(0), "", "Lunch", "12:00 in 10m\nKitchen", []string{}, map[string]dbus.Variant{}, int32(-1)},
+	}
+	for i := range expected {
+		if bus.methods[i] != "org.freedesktop.Notifications.Notify" {
+			t.Fatalf("unexpected method %s", bus.methods[i])
+		}
+		if !reflect.DeepEqual(bus.args[i], expected[i]) {
+			t.Fatalf("call %d: expected %#v, got %#v", i, expected[i], bus.args[i])
+		}
+	}
+	if len(bus.args) != len(expected) {
+		t.Fatalf("expected %d calls, got %d", len(expected), len(bus.args))
+	}
+}
+
+func TestDBusJoinAction(t *testing.T) {
+	bus := &fakeBus{}
+	n := newDBusNotifier(bus)
+	if err := n.notify(notification{key: "review", title: "Review", link: "https://meet.google.com/abc-defg-hij"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.notify(notification{key: "lunch", title: "Lunch"}); err != nil {
+		t.Fatal(err)
+	}
+
+	signals := make(chan *dbus.Signal, 4)
+	signals <- &dbus.Signal{Name: "org.freedesktop.Notifications.ActionInvoked", Body: []interface{}{uint32(2), "join"}}
+	signals <- &dbus.Signal{Name: "org.freedesktop.Notifications.NotificationClosed", Body: []interface{}{uint32(1), uint32(2)}}
+	signals <- &dbus.Signal{Name: "org.freedesktop.Notifications.ActionInvoked", Body: []interface{}{uint32(1), "default"}}
+	signals <- &dbus.Signal{Name: "org.freedesktop.Notifications.ActionInvoked", Body: []interface{}{uint32(1), "join"}}
+	close(signals)
+
+	var opened []string
+	n.handleSignals(signals, func(link string) error {
+		opened = append(opened, link)
+		return nil
+	})
+	if len(opened) != 1 || opened[0] != "https://meet.google.com/abc-defg-hij" {
+		t.Fatalf("only the Join action of the notification with link should open it, got %v", opened)
+	}
+}
+
+func TestDBusRemindersDelayedPoll(t *testing.T) {
+	bus := &fakeBus{}
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.Local)
+	event := reminderEvent("review", "Review", start, start.Add(time.Hour))
+	event.Raw.ConferenceData = &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/abc-defg-hij"}}}
+	r := reminders{
+		offsets:    []time.Duration{15 * time.Minute, 10 * time.Minute, 5 * time.Minute},
+		notifier:   newDBusNotifier(bus),
+		timeLayout: "15:04",
+		sent:       map[string]time.Time{},
+	}
+	// the daemon was suspended before the first offset, and woke up after the last one
+	for _, poll := range []time.Duration{-20 * time.Minute, -3 * time.Minute, -2 * time.Minute, -time.Minute} {
+		r.check([]render.Event{event}, start.Add(poll))
+	}
+	if len(bus.args) != 1 {
+		t.Fatalf("expected one notification, got %d", len(bus.args))
+	}
+	if body := bus.args[0][4]; body != "10:00 in 3m" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
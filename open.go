@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// open opens the link of the next event with xdg-open.
+func open(authOpts auth.Options, opts runOptions, preferLocation bool) error {
+	next, err := nextEvent(authOpts, opts, false)
+	if err != nil {
+		return err
+	}
+
+	link := next.Raw.HtmlLink
+	if preferLocation {
+		if geo := next.GeoURI(); geo != "" {
+			link = geo
+		}
+	}
+	return osutil.OpenLink(link)
+}
+
+// join opens the video conference of the running or the next meeting, or calls its dial-in number. With
+// preferLocation, the geo: URI of the location is opened if there is one.
+func join(authOpts auth.Options, opts runOptions, preferLocation bool) error {
+	next, err := nextEvent(authOpts, opts, true)
+	if err != nil {
+		return err
+	}
+	if preferLocation {
+		if geo := next.GeoURI(); geo != "" {
+			return osutil.OpenLink(geo)
+		}
+	}
+	if link := next.VideoLink(); link != "" {
+		return osutil.OpenLink(link)
+	}
+	if number, pin := next.DialIn(); number != "" {
+		// there may be no handler of tel: links on the desktop, the details are needed to dial manually
+		fmt.Printf("Dial-in: %s\n", number)
+		if pin != "" {
+			fmt.Printf("PIN: %s\n", pin)
+		}
+		if id := next.ConferenceID(); id != "" {
+			fmt.Printf("Meeting ID: %s\n", id)
+		}
+		link := "tel:" + strings.ReplaceAll(number, " ", "")
+		if pin != "" {
+			link += ",," + pin
+		}
+		return osutil.OpenLink(link)
+	}
+	return errs.Errorf("%s has no conference link", next.Raw.Summary)
+}
+
+// copyLink copies the conference link (or the calendar link) of the running or next meeting to the clipboard.
+func copyLink(authOpts auth.Options, opts runOptions) error {
+	next, err := nextEvent(authOpts, opts, true)
+	if err != nil {
+		return err
+	}
+	link := next.VideoLink()
+	if link == "" {
+		link = next.Raw.HtmlLink
+	}
+	return copyToClipboard(link)
+}
+
+// copyToClipboard uses wl-copy on Wayland and xclip on X11.
+func copyToClipboard(text string) error {
+	if text == "" {
+		return errs.Errorf("no link to copy")
+	}
+	cmd := exec.Command("xclip", "-selection", "clipboard")
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmd = exec.Command("wl-copy")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return errs.Wrap(cmd.Run())
+}
+
+// nextEvent returns the event shown in the bar. With preferRunning, a meeting in progress is returned even after the
+// grace period.
+func nextEvent(authOpts auth.Options, opts runOptions, preferRunning bool) (*render.Event, error) {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	events, err := fetch(ctx, calendars, now, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.ShowCurrent = opts.ShowCurrent || preferRunning
+	next := render.SelectNext(events, now, opts.Options)
+	if next == nil {
+		return nil, errs.Errorf("there is no next event")
+	}
+	return next, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/zeebo/errs/v2"
+)
+
+// setupOutlook authorizes the access to an outlook calendar of the config file.
+func setupOutlook(authOpts auth.Options, name string, deviceFlow bool) error {
+	configured, err := providers.ReadConfig(authOpts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	for _, config := range configured.Outlook {
+		if config.Name != name {
+			continue
+		}
+		if config.ClientID == "" {
+			return errs.Errorf("client-id of outlook calendar %q is missing", name)
+		}
+		token, err := auth.Authorize(context.Background(), config.OAuthConfig(), deviceFlow, config.DeviceCodeURL())
+		if err != nil {
+			return err
+		}
+		if err := providers.WriteOutlookToken(authOpts, config, token); err != nil {
+			return err
+		}
+		if token.RefreshToken == "" {
+			fmt.Println("Warning: there is no refresh token, offline_access is not granted")
+		}
+		return nil
+	}
+	return errs.Errorf("outlook calendar %q is not defined in %s", name, authOpts.ConfigFile)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"time"
+)
+
+// formatItem converts the bar item to the output format of the status bar. Some formats show the events, too.
+func formatItem(item render.BarItem, events []render.Event, now time.Time, opts runOptions) (string, error) {
+	colors, err := render.ParseColors(opts.colors)
+	if err != nil {
+		return "", err
+	}
+	switch opts.format {
+	case "waybar":
+		content, err := json.Marshal(item)
+		if err != nil {
+			return "", errs.Wrap(err)
+		}
+		return string(content), nil
+	case "polybar":
+		return render.Polybar(item, colors, opts.clickCommand), nil
+	case "i3blocks":
+		return render.I3Blocks(item, colors, opts.shortLength), nil
+	case "xmobar":
+		return render.Xmobar(item, colors, opts.clickCommand), nil
+	case "tmux":
+		return render.Tmux(item, colors), nil
+	case "plain":
+		return render.Plain(item, opts.plainSeparator, opts.maxWidth), nil
+	case "xbar":
+		return render.Xbar(item, events, colors, opts.Options), nil
+	case "eww":
+		return render.Eww(item, events, now, opts.Options)
+	case "yambar":
+		return render.Yambar(item, events, now, opts.Options), nil
+	default:
+		return "", errs.Errorf("unknown output format %q (use waybar, polybar, i3blocks, xmobar, tmux, plain, xbar, eww or yambar)", opts.format)
+	}
+}
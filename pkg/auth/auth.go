@@ -0,0 +1,151 @@
+// Package auth manages the OAuth2 credentials and tokens of the Google Calendar API: the interactive authorization
+// (loopback or device flow), and the token stores (plain or encrypted file, Secret Service keyring, pass and gpg).
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"io/ioutil"
+	"log"
+	"path"
+	"strings"
+)
+
+// AppName is the name of the application, used by the keyring, the notifications and the default directories.
+const AppName = "waybar-google-calendar-check"
+
+// Options defines how the Google API is accessed and where the credentials and the token are stored.
+type Options struct {
+	ConfigDir   string
+	UserAgent   string
+	TokenKeyCmd string
+	Scopes      []string
+	// ConfigFile contains the configuration of the other providers.
+	ConfigFile string
+	// TokenStore is the backend of the token: file, keyring, pass or gpg.
+	TokenStore   string
+	PassName     string
+	GPGRecipient string
+}
+
+// NewService creates an authenticated calendar client from the saved credentials and token.
+// The service (and the underlying http client) is created once and shared by all the calendar fetches of run and watch.
+func NewService(ctx context.Context, auth Options) (*calendar.Service, error) {
+	config, err := ReadCredentials(auth)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	token, err := ReadToken(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource := NewPersistingTokenSource(config.TokenSource(ctx, token), token, func(token *oauth2.Token) error {
+		return WriteToken(auth, token)
+	})
+	service, err := calendar.NewService(ctx, ServiceOptions(tokenSource, auth.UserAgent)...)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return service, nil
+}
+
+// ServiceOptions returns the client options of the Google API clients using the token source.
+func ServiceOptions(tokenSource oauth2.TokenSource, userAgent string) []option.ClientOption {
+	opts := []option.ClientOption{option.WithTokenSource(tokenSource)}
+	if userAgent != "" {
+		opts = append(opts, option.WithUserAgent(userAgent))
+	}
+	return opts
+}
+
+// ReadToken reads the saved token from the configured store.
+func ReadToken(auth Options) (*oauth2.Token, error) {
+	t := &oauth2.Token{}
+	content, err := readTokenContent(auth)
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(content, t)
+	if err != nil {
+		return t, errs.Wrap(err)
+	}
+	return t, nil
+}
+
+// GrantedScopes returns the scopes which are accepted by the user during the consent.
+func GrantedScopes(token *oauth2.Token) (string, bool) {
+	if token == nil {
+		return "", false
+	}
+	scopes, ok := token.Extra("scope").(string)
+	return scopes, ok
+}
+
+// openIDScopes are the OpenID Connect scopes, which are not prefixed with the URL of the Google APIs. Google reports
+// email and profile with their userinfo URLs in the granted scopes.
+var openIDScopes = map[string]string{
+	"openid":  "openid",
+	"email":   "https://www.googleapis.com/auth/userinfo.email",
+	"profile": "https://www.googleapis.com/auth/userinfo.profile",
+}
+
+// scopeURLs converts the short scope names (like calendar.readonly) to the full scope URLs.
+func scopeURLs(scopes []string) []string {
+	if len(scopes) == 0 {
+		return []string{calendar.CalendarReadonlyScope}
+	}
+	var res []string
+	for _, scope := range scopes {
+		if _, found := openIDScopes[scope]; !found && !strings.Contains(scope, "://") {
+			scope = "https://www.googleapis.com/auth/" + scope
+		}
+		res = append(res, scope)
+	}
+	return res
+}
+
+// MissingScopes returns the requested scopes which are not among the granted (space separated) scopes.
+func MissingScopes(requested []string, granted string) []string {
+	grantedScopes := map[string]bool{}
+	for _, scope := range strings.Fields(granted) {
+		grantedScopes[scope] = true
+	}
+	var missing []string
+	for _, scope := range requested {
+		if grantedScopes[scope] || grantedScopes[openIDScopes[scope]] {
+			continue
+		}
+		missing = append(missing, scope)
+	}
+	return missing
+}
+
+// WriteToken saves the token, encrypted if a key command is configured.
+func WriteToken(auth Options, token *oauth2.Token) error {
+	content, err := json.Marshal(token)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	return writeTokenContent(auth, content)
+}
+
+// ReadCredentials reads the OAuth2 client configuration from the credentials.json of the config dir.
+func ReadCredentials(auth Options) (*oauth2.Config, error) {
+	credentialFile := path.Join(auth.ConfigDir, "credentials.json")
+	content, err := ioutil.ReadFile(credentialFile)
+	if err != nil {
+		return nil, errs.Errorf("Couldn't read credentials file from %s: %v", credentialFile, err)
+	}
+
+	config, err := google.ConfigFromJSON(content, scopeURLs(auth.Scopes)...)
+	if err != nil {
+		log.Fatalf("Couldn't parse configuration: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestServiceOptionsUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+	}{
+		{name: "custom", userAgent: "acme-calendar/1.2"},
+		{name: "default", userAgent: AppName + "/dev"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var userAgent, authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.Header.Get("User-Agent")
+				authorization = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte(`{"items":[]}`))
+			}))
+			defer server.Close()
+
+			tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "secret"})
+			opts := append(ServiceOptions(tokenSource, tc.userAgent), option.WithEndpoint(server.URL))
+			service, err := calendar.NewService(context.Background(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := service.CalendarList.List().Do(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(userAgent, tc.userAgent) {
+				t.Fatalf("expected user agent %q, got %q", tc.userAgent, userAgent)
+			}
+			if authorization != "Bearer secret" {
+				t.Fatalf("the token source is not used, got authorization %q", authorization)
+			}
+		})
+	}
+}
+
+func TestScopeURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		expected []string
+	}{
+		{name: "default", expected: []string{"https://www.googleapis.com/auth/calendar.readonly"}},
+		{
+			name:     "short names",
+			scopes:   []string{"calendar.readonly", "calendar.events"},
+			expected: []string{"https://www.googleapis.com/auth/calendar.readonly", "https://www.googleapis.com/auth/calendar.events"},
+		},
+		{
+			name:     "urls",
+			scopes:   []string{"https://www.googleapis.com/auth/calendar"},
+			expected: []string{"https://www.googleapis.com/auth/calendar"},
+		},
+		{
+			name:     "openid connect",
+			scopes:   []string{"openid", "email", "profile", "calendar.readonly"},
+			expected: []string{"openid", "email", "profile", "https://www.googleapis.com/auth/calendar.readonly"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := strings.Join(scopeURLs(tc.scopes), " "); got != strings.Join(tc.expected, " ") {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		granted   string
+		missing   string
+	}{
+		{name: "same", requested: []string{"calendar.readonly"}, granted: "https://www.googleapis.com/auth/calendar.readonly"},
+		{name: "new scope", requested: []string{"calendar.readonly", "calendar.events"}, granted: "https://www.googleapis.com/auth/calendar.readonly", missing: "https://www.googleapis.com/auth/calendar.events"},
+		{name: "less scopes", requested: []string{"calendar.readonly"}, granted: "https://www.googleapis.com/auth/calendar.events https://www.googleapis.com/auth/calendar.readonly"},
+		{name: "openid connect", requested: []string{"openid", "email", "profile"}, granted: "openid https://www.googleapis.com/auth/userinfo.profile https://www.googleapis.com/auth/userinfo.email\n"},
+		{name: "nothing granted", requested: []string{"calendar.readonly"}, missing: "https://www.googleapis.com/auth/calendar.readonly"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if missing := strings.Join(MissingScopes(scopeURLs(tc.requested), tc.granted), " "); missing != tc.missing {
+				t.Fatalf("expected %q, got %q", tc.missing, missing)
+			}
+		})
+	}
+}
+
+func TestReadCredentialsScopes(t *testing.T) {
+	dir := t.TempDir()
+	credentials := `{"installed":{"client_id":"id","client_secret":"secret","auth_uri":"https://accounts.google.com/o/oauth2/auth","token_uri":"https://oauth2.googleapis.com/token","redirect_uris":["http://localhost"]}}`
+	if err := ioutil.WriteFile(path.Join(dir, "credentials.json"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ReadCredentials(Options{ConfigDir: dir, Scopes: []string{"calendar.readonly", "calendar.events"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "https://www.googleapis.com/auth/calendar.readonly https://www.googleapis.com/auth/calendar.events"
+	if got := strings.Join(config.Scopes, " "); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if authURL := config.AuthCodeURL("state"); !strings.Contains(authURL, "calendar.events") {
+		t.Fatalf("the scopes are not requested in %s", authURL)
+	}
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deviceScopeError is returned when the device authorization endpoint rejects the scopes.
+func deviceScopeError(scope string) error {
+	return errs.Errorf("scope %s is not allowed in the device flow, run setup-outlook without --device-flow instead "+
+		"(the authorization URL can be opened on another device, then the redirected URL pasted back to the terminal)", scope)
+}
+
+// deviceCode is the response of the device authorization request.
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	// VerificationURI is the standard name of the field (used by Microsoft).
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+	Error           string `json:"error"`
+}
+
+// deviceToken is the response of the token endpoint during the device flow.
+type deviceToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+}
+
+// authorizeDevice implements the OAuth device authorization grant: the user enters a short code on another device.
+func authorizeDevice(ctx context.Context, client *http.Client, config *oauth2.Config, codeURL string) (*oauth2.Token, error) {
+	var code deviceCode
+	err := postForm(ctx, client, codeURL, url.Values{
+		"client_id": {config.ClientID},
+		"scope":     {strings.Join(config.Scopes, " ")},
+	}, &code)
+	if err != nil {
+		return nil, err
+	}
+	if code.Error == "invalid_scope" {
+		return nil, deviceScopeError(strings.Join(config.Scopes, " "))
+	}
+	if code.Error != "" {
+		return nil, errs.Errorf("device authorization is failed: %s", code.Error)
+	}
+	if code.DeviceCode == "" {
+		return nil, errs.Errorf("device code is missing from the response")
+	}
+
+	verification := code.VerificationURL
+	if verification == "" {
+		verification = code.VerificationURI
+	}
+	fmt.Printf("Open %s on any device and enter the code: %s\n", verification, code.UserCode)
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, errs.Wrap(ctx.Err())
+		}
+
+		var token deviceToken
+		err := postForm(ctx, client, config.Endpoint.TokenURL, url.Values{
+			"client_id":     {config.ClientID},
+			"client_secret": {config.ClientSecret},
+			"device_code":   {code.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &token)
+		if err != nil {
+			return nil, err
+		}
+		switch token.Error {
+		case "":
+			return (&oauth2.Token{
+				AccessToken:  token.AccessToken,
+				RefreshToken: token.RefreshToken,
+				TokenType:    token.TokenType,
+				Expiry:       time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+			}).WithExtra(map[string]interface{}{"scope": token.Scope}), nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, errs.Errorf("device authorization is failed: %s", token.Error)
+		}
+	}
+	return nil, errs.Errorf("device code is expired, please run setup again")
+}
+
+// postForm posts the form and decodes the JSON response. Error responses with JSON body are decoded, too.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return errs.Errorf("invalid response from %s (%s): %v", endpoint, resp.Status, err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeDeviceInvalidScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_scope"}`))
+	}))
+	defer server.Close()
+
+	config := &oauth2.Config{ClientID: "id", Scopes: []string{"Calendars.Read"}}
+	_, err := authorizeDevice(context.Background(), server.Client(), config, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "without --device-flow") {
+		t.Fatalf("invalid_scope should point to the other flow, got %v", err)
+	}
+}
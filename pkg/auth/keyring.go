@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/zeebo/errs/v2"
+	"time"
+)
+
+// secret is the Secret struct of the freedesktop Secret Service API.
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// keyring stores the token in the default collection of the Secret Service (GNOME Keyring, KWallet).
+type keyring struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+	// attributes identify the item of the token.
+	attributes map[string]string
+}
+
+func openKeyring(configDir string) (*keyring, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, errs.Errorf("session bus is not available: %v", err)
+	}
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err = conn.Object(secretService, secretPath).Call("org.freedesktop.Secret.Service.OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session)
+	if err != nil {
+		return nil, errs.Errorf("secret service is not available: %v", err)
+	}
+	return &keyring{
+		conn:    conn,
+		session: session,
+		attributes: map[string]string{
+			"application": AppName,
+			"config-dir":  configDir,
+		},
+	}, nil
+}
+
+// read returns the saved token, or nil if there is no saved token.
+func (k *keyring) read() ([]byte, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := k.conn.Object(secretService, secretPath).Call("org.freedesktop.Secret.Service.SearchItems", 0, k.attributes).Store(&unlocked, &locked)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		var promptPath dbus.ObjectPath
+		err = k.conn.Object(secretService, secretPath).Call("org.freedesktop.Secret.Service.Unlock", 0, locked[:1]).Store(&unlocked, &promptPath)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if err := k.prompt(promptPath); err != nil {
+			return nil, err
+		}
+		unlocked = locked[:1]
+	}
+	if len(unlocked) == 0 {
+		return nil, nil
+	}
+	var value secret
+	err = k.conn.Object(secretService, unlocked[0]).Call("org.freedesktop.Secret.Item.GetSecret", 0, k.session).Store(&value)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return value.Value, nil
+}
+
+// write saves (or replaces) the token.
+func (k *keyring) write(content []byte) error {
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant(AppName + " token"),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(k.attributes),
+	}
+	value := secret{
+		Session:     k.session,
+		Value:       content,
+		ContentType: "application/json",
+	}
+	var item, promptPath dbus.ObjectPath
+	err := k.conn.Object(secretService, secretCollection).Call("org.freedesktop.Secret.Collection.CreateItem", 0, properties, value, true).Store(&item, &promptPath)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	return k.prompt(promptPath)
+}
+
+// prompt shows the unlock dialog of the keyring (if required) and waits for the result.
+func (k *keyring) prompt(path dbus.ObjectPath) error {
+	if path == "" || path == "/" {
+		return nil
+	}
+	err := k.conn.AddMatchSignal(dbus.WithMatchObjectPath(path), dbus.WithMatchInterface("org.freedesktop.Secret.Prompt"), dbus.WithMatchMember("Completed"))
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	k.conn.Signal(signals)
+	defer k.conn.RemoveSignal(signals)
+
+	err = k.conn.Object(secretService, path).Call("org.freedesktop.Secret.Prompt.Prompt", 0, "").Err
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	timeout := time.After(2 * time.Minute)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || len(signal.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return errs.Errorf("keyring unlock is dismissed")
+			}
+			return nil
+		case <-timeout:
+			return errs.Errorf("keyring is not unlocked in time")
+		}
+	}
+}
+
+const (
+	secretService    = "org.freedesktop.secrets"
+	secretPath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+)
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Authorize gets a new token with the interactive consent of the user. codeURL is the device authorization endpoint
+// of the provider, used with the device flow.
+func Authorize(ctx context.Context, config *oauth2.Config, deviceFlow bool, codeURL string) (*oauth2.Token, error) {
+	if deviceFlow {
+		return authorizeDevice(ctx, http.DefaultClient, config, codeURL)
+	}
+	authCode, err := authorizeWithLoopback(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	token, err := config.Exchange(ctx, authCode)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return token, nil
+}
+
+// authorizeWithLoopback gets an authorization code with a temporary local HTTP server as redirect URL.
+// If the browser can't be opened, the code (or the full redirected URL) can be pasted to the terminal.
+func authorizeWithLoopback(ctx context.Context, config *oauth2.Config) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	defer func() { _ = listener.Close() }()
+	config.RedirectURL = "http://" + listener.Addr().String() + "/"
+
+	state, err := randomState()
+	if err != nil {
+		return "", err
+	}
+
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			code, err := codeFromQuery(r.URL.Query(), state)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				failures <- err
+				return
+			}
+			_, _ = fmt.Fprintln(w, "Authorization is finished, you can close this window.")
+			codes <- code
+		}),
+	}
+	go func() { _ = server.Serve(listener) }()
+	defer func() { _ = server.Close() }()
+
+	// the consent is forced to get a refresh token even if the user already authorized the application (eg. with
+	// other scopes)
+	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	if err := osutil.OpenLink(authURL); err != nil {
+		fmt.Println("Open the following URL in a browser:")
+		fmt.Println(authURL)
+		fmt.Println("If the redirect fails (browser on other machine), paste the code or the full URL of the redirected page:")
+		go func() {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				failures <- errs.Wrap(err)
+				return
+			}
+			code, err := parsePastedCode(strings.TrimSpace(line), state)
+			if err != nil {
+				failures <- err
+				return
+			}
+			codes <- code
+		}()
+	} else {
+		fmt.Println("Finish the authorization in the browser.")
+	}
+
+	select {
+	case code := <-codes:
+		return code, nil
+	case err := <-failures:
+		return "", err
+	case <-ctx.Done():
+		return "", errs.Wrap(ctx.Err())
+	}
+}
+
+// parsePastedCode accepts either the bare code or the full redirected URL.
+func parsePastedCode(pasted string, state string) (string, error) {
+	if !strings.Contains(pasted, "://") {
+		return pasted, nil
+	}
+	u, err := url.Parse(pasted)
+	if err != nil {
+		return "", errs.Errorf("invalid URL %q: %v", pasted, err)
+	}
+	return codeFromQuery(u.Query(), state)
+}
+
+func codeFromQuery(query url.Values, state string) (string, error) {
+	if errMsg := query.Get("error"); errMsg != "" {
+		return "", errs.Errorf("authorization is failed: %s", errMsg)
+	}
+	if query.Get("state") != state {
+		return "", errs.Errorf("authorization state mismatch")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errs.Errorf("authorization code is missing")
+	}
+	return code, nil
+}
+
+func randomState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errs.Wrap(err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"bytes"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"os"
+	"os/exec"
+	"path"
+)
+
+// readPassToken reads the token from the pass password store (decrypted by gpg-agent).
+func readPassToken(auth Options) ([]byte, error) {
+	out, err := osutil.CommandOutput(exec.Command("pass", "show", auth.PassName))
+	if err != nil {
+		return nil, errs.Errorf("couldn't read token from pass %s: %v", auth.PassName, err)
+	}
+	return out, nil
+}
+
+// writePassToken saves the token to the pass password store. Only the public key is required, no passphrase prompt.
+func writePassToken(auth Options, content []byte) error {
+	cmd := exec.Command("pass", "insert", "--multiline", "--force", auth.PassName)
+	cmd.Stdin = bytes.NewReader(content)
+	if _, err := osutil.CommandOutput(cmd); err != nil {
+		return errs.Errorf("couldn't save token to pass %s: %v", auth.PassName, err)
+	}
+	return nil
+}
+
+func gpgTokenFile(auth Options) string {
+	return path.Join(auth.ConfigDir, "token.json.gpg")
+}
+
+// readGPGToken decrypts the token.json.gpg file of the config dir.
+func readGPGToken(auth Options) ([]byte, error) {
+	if _, err := os.Stat(gpgTokenFile(auth)); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	out, err := osutil.CommandOutput(exec.Command("gpg", "--quiet", "--batch", "--decrypt", gpgTokenFile(auth)))
+	if err != nil {
+		return nil, errs.Errorf("couldn't decrypt %s: %v", gpgTokenFile(auth), err)
+	}
+	return out, nil
+}
+
+// writeGPGToken encrypts the token to the recipient and saves it to token.json.gpg.
+func writeGPGToken(auth Options, content []byte) error {
+	if auth.GPGRecipient == "" {
+		return errs.Errorf("--gpg-recipient is required to save the token with gpg")
+	}
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--yes", "--encrypt", "--recipient", auth.GPGRecipient, "--output", "-")
+	cmd.Stdin = bytes.NewReader(content)
+	out, err := osutil.CommandOutput(cmd)
+	if err != nil {
+		return errs.Errorf("couldn't encrypt token: %v", err)
+	}
+	return osutil.WriteFileAtomic(gpgTokenFile(auth), out, 0600)
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"sync"
+)
+
+// encryptedPrefix marks the token files encrypted with the key of the --token-key-cmd.
+var encryptedPrefix = []byte("encrypted:v1:")
+
+func isEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, encryptedPrefix)
+}
+
+// tokenKey executes the key command and derives the AES-256 key from its output.
+func tokenKey(keyCmd string) ([]byte, error) {
+	out, err := exec.Command("sh", "-c", keyCmd).Output()
+	if err != nil {
+		return nil, errs.Errorf("couldn't execute token key command %q: %v", keyCmd, err)
+	}
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, errs.Errorf("token key command %q returned empty key", keyCmd)
+	}
+	key := sha256.Sum256(out)
+	return key[:], nil
+}
+
+func tokenCipher(keyCmd string) (cipher.AEAD, error) {
+	key, err := tokenKey(keyCmd)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return aead, nil
+}
+
+func encryptToken(plain []byte, keyCmd string) ([]byte, error) {
+	aead, err := tokenCipher(keyCmd)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errs.Wrap(err)
+	}
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+	return append(append([]byte{}, encryptedPrefix...), base64.StdEncoding.EncodeToString(sealed)...), nil
+}
+
+func decryptToken(content []byte, keyCmd string) ([]byte, error) {
+	aead, err := tokenCipher(keyCmd)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content[len(encryptedPrefix):])))
+	if err != nil {
+		return nil, errs.Errorf("invalid encrypted token: %v", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errs.Errorf("invalid encrypted token: too short")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errs.Errorf("token couldn't be decrypted (wrong key?): %v", err)
+	}
+	return plain, nil
+}
+
+// persistingTokenSource saves the token whenever it's refreshed, so the next invocation can reuse the access token.
+type persistingTokenSource struct {
+	source oauth2.TokenSource
+	save   func(token *oauth2.Token) error
+
+	mu   sync.Mutex
+	last string
+}
+
+// NewPersistingTokenSource wraps the source of the token, save is called with the refreshed tokens.
+func NewPersistingTokenSource(source oauth2.TokenSource, token *oauth2.Token, save func(token *oauth2.Token) error) oauth2.TokenSource {
+	return &persistingTokenSource{
+		source: source,
+		save:   save,
+		last:   token.AccessToken,
+	}
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := p.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if token.AccessToken != p.last {
+		if err := p.save(token); err != nil {
+			log.Printf("refreshed token couldn't be saved: %v", err)
+		} else {
+			p.last = token.AccessToken
+		}
+	}
+	return token, nil
+}
+
+// readTokenContent returns the JSON of the saved token from the configured store.
+func readTokenContent(auth Options) ([]byte, error) {
+	switch auth.TokenStore {
+	case "pass":
+		return readPassToken(auth)
+	case "gpg":
+		return readGPGToken(auth)
+	}
+	if auth.TokenStore == "keyring" {
+		content, err := readKeyringToken(auth)
+		if err == nil && content != nil {
+			return content, nil
+		}
+		if err != nil {
+			log.Printf("couldn't read token from the keyring, using the token file: %v", err)
+		}
+	}
+	return ReadTokenFile(auth, "token.json")
+}
+
+// ReadTokenFile reads a token file of the config dir, decrypting it if it's encrypted.
+func ReadTokenFile(auth Options, name string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path.Join(auth.ConfigDir, name))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if auth.TokenKeyCmd != "" && isEncrypted(content) {
+		return decryptToken(content, auth.TokenKeyCmd)
+	}
+	return content, nil
+}
+
+// writeTokenContent saves the JSON of the token to the configured store.
+func writeTokenContent(auth Options, content []byte) error {
+	switch auth.TokenStore {
+	case "", "file":
+	case "keyring":
+		err := writeKeyringToken(auth, content)
+		if err == nil {
+			// the keyring has the token, don't keep the plaintext copy
+			if err := os.Remove(path.Join(auth.ConfigDir, "token.json")); err != nil && !os.IsNotExist(err) {
+				log.Printf("couldn't remove the token file: %v", err)
+			}
+			return nil
+		}
+		log.Printf("couldn't save token to the keyring, using the token file: %v", err)
+	case "pass":
+		return writePassToken(auth, content)
+	case "gpg":
+		return writeGPGToken(auth, content)
+	default:
+		return errs.Errorf("unknown token store %q (use file, keyring, pass or gpg)", auth.TokenStore)
+	}
+	return WriteTokenFile(auth, "token.json", content)
+}
+
+// WriteTokenFile saves a token file to the config dir, encrypted if there is a token key command.
+func WriteTokenFile(auth Options, name string, content []byte) error {
+	if auth.TokenKeyCmd != "" {
+		var err error
+		content, err = encryptToken(content, auth.TokenKeyCmd)
+		if err != nil {
+			return err
+		}
+	}
+	return osutil.WriteFileAtomic(path.Join(auth.ConfigDir, name), content, 0600)
+}
+
+func readKeyringToken(auth Options) ([]byte, error) {
+	k, err := openKeyring(auth.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	return k.read()
+}
+
+func writeKeyringToken(auth Options, content []byte) error {
+	k, err := openKeyring(auth.ConfigDir)
+	if err != nil {
+		return err
+	}
+	return k.write(content)
+}
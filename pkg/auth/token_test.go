@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"bytes"
+	"golang.org/x/oauth2"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestTokenEncryption(t *testing.T) {
+	token := &oauth2.Token{AccessToken: "access-secret", RefreshToken: "refresh-secret", TokenType: "Bearer"}
+	tests := []struct {
+		name      string
+		writeKey  string
+		readKey   string
+		encrypted bool
+		invalid   bool
+	}{
+		{name: "encrypted round trip", writeKey: "echo secret", readKey: "echo secret", encrypted: true},
+		{name: "plaintext without key"},
+		{name: "plaintext is read with key", readKey: "echo secret"},
+		{name: "wrong key", writeKey: "echo secret", readKey: "echo other", encrypted: true, invalid: true},
+		{name: "encrypted without key", writeKey: "echo secret", encrypted: true, invalid: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := WriteToken(Options{ConfigDir: dir, TokenKeyCmd: tc.writeKey}, token); err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadFile(path.Join(dir, "token.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isEncrypted(content) != tc.encrypted {
+				t.Fatalf("expected encrypted=%v, got %q", tc.encrypted, content)
+			}
+			if tc.encrypted && bytes.Contains(content, []byte("secret")) {
+				t.Fatalf("the encrypted token file contains the plaintext token: %q", content)
+			}
+
+			read, err := ReadToken(Options{ConfigDir: dir, TokenKeyCmd: tc.readKey})
+			if (err != nil) != tc.invalid {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.invalid && (read.AccessToken != token.AccessToken || read.RefreshToken != token.RefreshToken) {
+				t.Fatalf("expected %+v, got %+v", token, read)
+			}
+		})
+	}
+}
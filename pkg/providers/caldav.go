@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CalDAVConfig is a [[caldav]] section of the config file.
+type CalDAVConfig struct {
+	// Name identifies the calendar in the cache.
+	Name string `toml:"name"`
+	// URL is the address of the calendar collection.
+	URL      string `toml:"url"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// PasswordCmd prints the (app) password, like pass show nextcloud.
+	PasswordCmd string `toml:"password-cmd"`
+	// Email is the address of the current user, to find the own responses.
+	Email string `toml:"email"`
+}
+
+// CalDAV reads the events of a CalDAV calendar (Nextcloud, Radicale, Fastmail...).
+type CalDAV struct {
+	config CalDAVConfig
+	client *http.Client
+}
+
+// NewCalDAV creates the client of the calendar. The password command is executed here, only once.
+func NewCalDAV(config CalDAVConfig) (CalDAV, error) {
+	if config.URL == "" {
+		return CalDAV{}, errs.Errorf("url of caldav calendar %q is missing", config.Name)
+	}
+	if config.Name == "" {
+		config.Name = config.URL
+	}
+	if config.PasswordCmd != "" {
+		out, err := osutil.CommandOutput(exec.Command("sh", "-c", config.PasswordCmd))
+		if err != nil {
+			return CalDAV{}, errs.Errorf("couldn't get password of caldav calendar %q: %v", config.Name, err)
+		}
+		config.Password = strings.TrimSpace(string(out))
+	}
+	return CalDAV{
+		config: config,
+		client: &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (c CalDAV) Name() string {
+	return "caldav-" + c.config.Name
+}
+
+// calendarQuery asks the events of the time range, with the recurring events expanded by the server.
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <c:calendar-data>
+      <c:expand start="%[1]s" end="%[2]s"/>
+    </c:calendar-data>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="%[1]s" end="%[2]s"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+type multistatus struct {
+	Responses []struct {
+		Propstats []struct {
+			CalendarData string `xml:"prop>calendar-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (c CalDAV) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	body := fmt.Sprintf(calendarQuery, from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"))
+	req, err := http.NewRequest("REPORT", c.config.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, errs.Errorf("caldav calendar %q returned %s", c.config.Name, resp.Status)
+	}
+
+	result := multistatus{}
+	if err := xml.Unmarshal(content, &result); err != nil {
+		return nil, errs.Errorf("invalid response of caldav calendar %q: %v", c.config.Name, err)
+	}
+	var res []*calendar.Event
+	for _, response := range result.Responses {
+		for _, propstat := range response.Propstats {
+			if strings.TrimSpace(propstat.CalendarData) == "" {
+				continue
+			}
+			events, err := icsCalendarEvents(propstat.CalendarData, from, to, c.config.Email)
+			if err != nil {
+				return nil, errs.Errorf("invalid event in caldav calendar %q: %v", c.config.Name, err)
+			}
+			res = append(res, events...)
+		}
+	}
+	return res, nil
+}
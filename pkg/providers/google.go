@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"context"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"strings"
+	"time"
+)
+
+// Google is one calendar of a Google account.
+type Google struct {
+	Service *calendar.Service
+	ID      string
+	// Account is the name of the account when the events of more accounts are merged.
+	Account string
+	// SharedPropertyFilter and PrivatePropertyFilter restrict the events to the ones with the key=value extended
+	// properties.
+	SharedPropertyFilter  []string
+	PrivatePropertyFilter []string
+	// SyncDir is the directory of the sync state, incremental sync is used if it's set.
+	SyncDir string
+}
+
+// Name identifies the calendar in the cache files and in the events.
+func (g Google) Name() string {
+	if g.Account == "" {
+		return g.ID
+	}
+	return g.Account + "/" + g.ID
+}
+
+// Events returns the events with one query, or with incremental sync if SyncDir is set.
+func (g Google) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	if g.SyncDir != "" {
+		return syncCalendar(ctx, g, from, to)
+	}
+	return queryCalendar(ctx, g, from, to)
+}
+
+// queryCalendar returns the raw events of one calendar from the API.
+func queryCalendar(ctx context.Context, cal Google, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	for _, filter := range append(cal.SharedPropertyFilter, cal.PrivatePropertyFilter...) {
+		if !strings.Contains(filter, "=") {
+			return nil, errs.Errorf("invalid extended property filter %q, use key=value", filter)
+		}
+	}
+	call := cal.Service.Events.List(cal.ID).TimeMin(from.Format(time.RFC3339)).SingleEvents(true).TimeMax(to.Format(time.RFC3339)).ShowDeleted(false)
+	if len(cal.SharedPropertyFilter) > 0 {
+		call = call.SharedExtendedProperty(cal.SharedPropertyFilter...)
+	}
+	if len(cal.PrivatePropertyFilter) > 0 {
+		call = call.PrivateExtendedProperty(cal.PrivatePropertyFilter...)
+	}
+	events, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	return events.Items, nil
+}
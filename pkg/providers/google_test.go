@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryCalendarExtendedPropertyFilter(t *testing.T) {
+	all := []*calendar.Event{
+		{Id: "1", Summary: "Platform sync", ExtendedProperties: &calendar.EventExtendedProperties{Shared: map[string]string{"team": "platform"}}},
+		{Id: "2", Summary: "Infra sync", ExtendedProperties: &calendar.EventExtendedProperties{Shared: map[string]string{"team": "infra"}}},
+		{Id: "3", Summary: "Ticket review", ExtendedProperties: &calendar.EventExtendedProperties{Private: map[string]string{"ticket": "OPS-1"}}},
+		{Id: "4", Summary: "Lunch"},
+	}
+	// matches emulates the server side filtering of the Calendar API
+	matches := func(event *calendar.Event, shared []string, private []string) bool {
+		for _, filter := range shared {
+			kv := strings.SplitN(filter, "=", 2)
+			if event.ExtendedProperties == nil || event.ExtendedProperties.Shared[kv[0]] != kv[1] {
+				return false
+			}
+		}
+		for _, filter := range private {
+			kv := strings.SplitN(filter, "=", 2)
+			if event.ExtendedProperties == nil || event.ExtendedProperties.Private[kv[0]] != kv[1] {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name     string
+		shared   []string
+		private  []string
+		expected string
+		invalid  bool
+	}{
+		{name: "no filter", expected: "Platform sync,Infra sync,Ticket review,Lunch"},
+		{name: "shared", shared: []string{"team=platform"}, expected: "Platform sync"},
+		{name: "private", private: []string{"ticket=OPS-1"}, expected: "Ticket review"},
+		{name: "no match", shared: []string{"team=sales"}},
+		{name: "invalid filter", shared: []string{"team"}, invalid: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var shared, private []string
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				shared = r.URL.Query()["sharedExtendedProperty"]
+				private = r.URL.Query()["privateExtendedProperty"]
+				res := calendar.Events{}
+				for _, event := range all {
+					if matches(event, shared, private) {
+						res.Items = append(res.Items, event)
+					}
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(res)
+			}))
+			defer server.Close()
+			service, err := calendar.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cal := Google{Service: service, ID: "primary", SharedPropertyFilter: tc.shared, PrivatePropertyFilter: tc.private}
+			from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+			events, err := cal.Events(context.Background(), from, from.AddDate(0, 0, 1))
+			if tc.invalid {
+				if err == nil || requests > 0 {
+					t.Fatalf("invalid filter should be rejected before the query, got %v after %d requests", err, requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(shared, ",") != strings.Join(tc.shared, ",") || strings.Join(private, ",") != strings.Join(tc.private, ",") {
+				t.Fatalf("expected query filters %v/%v, got %v/%v", tc.shared, tc.private, shared, private)
+			}
+			var summaries []string
+			for _, event := range events {
+				summaries = append(summaries, event.Summary)
+			}
+			if got := strings.Join(summaries, ","); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
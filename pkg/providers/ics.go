@@ -0,0 +1,368 @@
+package providers
+
+import (
+	"bufio"
+	"github.com/teambition/rrule-go"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsProperty is one content line of an iCalendar file, like DTSTART;TZID=Europe/Berlin:20210601T100000.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icsComponent is a BEGIN/END block of an iCalendar file (VCALENDAR, VEVENT...).
+type icsComponent struct {
+	name       string
+	properties []icsProperty
+	children   []*icsComponent
+}
+
+// parseICS parses the (unfolded) content lines of an iCalendar file.
+func parseICS(content string) (*icsComponent, error) {
+	root := &icsComponent{}
+	stack := []*icsComponent{root}
+	for _, line := range unfoldICS(content) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseICSLine(line)
+		if err != nil {
+			return nil, err
+		}
+		current := stack[len(stack)-1]
+		switch prop.name {
+		case "BEGIN":
+			child := &icsComponent{name: strings.ToUpper(prop.value)}
+			current.children = append(current.children, child)
+			stack = append(stack, child)
+		case "END":
+			if len(stack) == 1 || current.name != strings.ToUpper(prop.value) {
+				return nil, errs.Errorf("unexpected END:%s in iCalendar", prop.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			current.properties = append(current.properties, prop)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, errs.Errorf("unterminated %s in iCalendar", stack[len(stack)-1].name)
+	}
+	return root, nil
+}
+
+// unfoldICS joins the continuation lines (starting with space or tab) to the previous line.
+func unfoldICS(content string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func parseICSLine(line string) (icsProperty, error) {
+	prop := icsProperty{params: map[string]string{}}
+	// the value starts at the first colon which is not in a quoted parameter value
+	quoted := false
+	split := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		return prop, errs.Errorf("invalid iCalendar line %q", line)
+	}
+	prop.value = line[split+1:]
+	parts := strings.Split(line[:split], ";")
+	prop.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			prop.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return prop, nil
+}
+
+func (c *icsComponent) property(name string) *icsProperty {
+	for i := range c.properties {
+		if c.properties[i].name == name {
+			return &c.properties[i]
+		}
+	}
+	return nil
+}
+
+func (c *icsComponent) text(name string) string {
+	if prop := c.property(name); prop != nil {
+		return unescapeICSText(prop.value)
+	}
+	return ""
+}
+
+// events returns the VEVENT components of the calendar (at any depth).
+func (c *icsComponent) events() []*icsComponent {
+	var res []*icsComponent
+	for _, child := range c.children {
+		if child.name == "VEVENT" {
+			res = append(res, child)
+		}
+		res = append(res, child.events()...)
+	}
+	return res
+}
+
+var icsTextEscapes = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeICSText(value string) string {
+	return icsTextEscapes.Replace(value)
+}
+
+// icsTime parses a DATE or DATE-TIME property. Floating times and unknown timezones are local.
+func icsTime(prop *icsProperty) (t time.Time, allDay bool, err error) {
+	if prop.params["VALUE"] == "DATE" || len(prop.value) == 8 {
+		t, err = time.ParseInLocation("20060102", prop.value, time.Local)
+		return t, true, errs.Wrap(err)
+	}
+	if strings.HasSuffix(prop.value, "Z") {
+		t, err = time.Parse("20060102T150405Z", prop.value)
+		return t, false, errs.Wrap(err)
+	}
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	// the time is kept in its own location, so the recurrences follow the daylight saving time of the event
+	t, err = time.ParseInLocation("20060102T150405", prop.value, loc)
+	return t, false, errs.Wrap(err)
+}
+
+var icsDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseICSDuration parses the DURATION values, like PT1H30M or P1D.
+func parseICSDuration(value string) (time.Duration, error) {
+	match := icsDurationPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, errs.Errorf("invalid iCalendar duration %q", value)
+	}
+	var res time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return 0, errs.Wrap(err)
+		}
+		res += time.Duration(n) * unit
+	}
+	if match[1] == "-" {
+		res = -res
+	}
+	return res, nil
+}
+
+// icsEventTimes returns the start and the end of the VEVENT. The end is computed from the DURATION if DTEND is missing.
+func icsEventTimes(component *icsComponent) (start time.Time, end time.Time, allDay bool, err error) {
+	dtstart := component.property("DTSTART")
+	if dtstart == nil {
+		return start, end, false, errs.Errorf("event %s has no DTSTART", component.text("UID"))
+	}
+	start, allDay, err = icsTime(dtstart)
+	if err != nil {
+		return start, end, false, err
+	}
+	switch {
+	case component.property("DTEND") != nil:
+		end, _, err = icsTime(component.property("DTEND"))
+	case component.property("DURATION") != nil:
+		var d time.Duration
+		d, err = parseICSDuration(component.property("DURATION").value)
+		end = start.Add(d)
+	case allDay:
+		end = start.AddDate(0, 0, 1)
+	default:
+		end = start
+	}
+	return start, end, allDay, err
+}
+
+// icsEvent converts a VEVENT to the structure of the Google Calendar API, to render it the same way.
+// self is the email address of the current user, to find the own attendee entry.
+func icsEvent(component *icsComponent, start time.Time, end time.Time, allDay bool, self string) *calendar.Event {
+	uid := component.text("UID")
+	event := &calendar.Event{
+		Id:          uid + "/" + start.UTC().Format("20060102T150405Z"),
+		ICalUID:     uid,
+		Summary:     component.text("SUMMARY"),
+		Location:    component.text("LOCATION"),
+		Description: component.text("DESCRIPTION"),
+		HtmlLink:    component.text("URL"),
+		Status:      strings.ToLower(component.text("STATUS")),
+		Start:       icsEventDateTime(start, allDay),
+		End:         icsEventDateTime(end, allDay),
+	}
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	if organizer := component.property("ORGANIZER"); organizer != nil {
+		event.Organizer = &calendar.EventOrganizer{
+			Email:       icsEmail(organizer.value),
+			DisplayName: organizer.params["CN"],
+		}
+	}
+	for _, prop := range component.properties {
+		if prop.name != "ATTENDEE" {
+			continue
+		}
+		email := icsEmail(prop.value)
+		event.Attendees = append(event.Attendees, &calendar.EventAttendee{
+			Email:          email,
+			DisplayName:    prop.params["CN"],
+			ResponseStatus: icsResponseStatus(prop.params["PARTSTAT"]),
+			Resource:       prop.params["CUTYPE"] == "ROOM" || prop.params["CUTYPE"] == "RESOURCE",
+			Self:           self != "" && strings.EqualFold(email, self),
+		})
+	}
+	return event
+}
+
+func icsEventDateTime(t time.Time, allDay bool) *calendar.EventDateTime {
+	if allDay {
+		return &calendar.EventDateTime{Date: t.Format("2006-01-02")}
+	}
+	return &calendar.EventDateTime{DateTime: t.Format(time.RFC3339)}
+}
+
+func icsEmail(value string) string {
+	if strings.HasPrefix(strings.ToLower(value), "mailto:") {
+		return value[len("mailto:"):]
+	}
+	return value
+}
+
+// icsResponseStatus converts the PARTSTAT values to the response statuses of the Google Calendar API.
+func icsResponseStatus(partstat string) string {
+	switch strings.ToUpper(partstat) {
+	case "ACCEPTED":
+		return "accepted"
+	case "DECLINED":
+		return "declined"
+	case "TENTATIVE":
+		return "tentative"
+	default:
+		return "needsAction"
+	}
+}
+
+// icsCalendarEvents returns the events of the iCalendar data between from and to. Recurring events are expanded.
+func icsCalendarEvents(content string, from time.Time, to time.Time, self string) ([]*calendar.Event, error) {
+	root, err := parseICS(content)
+	if err != nil {
+		return nil, err
+	}
+	// the modified occurrences of the recurring events, by UID and original start
+	overrides := map[string]bool{}
+	for _, component := range root.events() {
+		if prop := component.property("RECURRENCE-ID"); prop != nil {
+			if t, _, err := icsTime(prop); err == nil {
+				overrides[recurrenceKey(component.text("UID"), t)] = true
+			}
+		}
+	}
+
+	var res []*calendar.Event
+	for _, component := range root.events() {
+		start, end, allDay, err := icsEventTimes(component)
+		if err != nil {
+			return nil, err
+		}
+		if component.property("RRULE") == nil || component.property("RECURRENCE-ID") != nil {
+			if overlaps(start, end, from, to) {
+				res = append(res, icsEvent(component, start, end, allDay, self))
+			}
+			continue
+		}
+		occurrences, err := icsOccurrences(component, start, from.Add(-end.Sub(start)), to)
+		if err != nil {
+			return nil, errs.Errorf("invalid recurrence of %s: %v", component.text("UID"), err)
+		}
+		for _, occurrence := range occurrences {
+			occurrenceEnd := occurrence.Add(end.Sub(start))
+			if allDay {
+				occurrenceEnd = occurrence.AddDate(0, 0, int(end.Sub(start).Hours()/24+0.5))
+			}
+			if overrides[recurrenceKey(component.text("UID"), occurrence)] || !overlaps(occurrence, occurrenceEnd, from, to) {
+				continue
+			}
+			res = append(res, icsEvent(component, occurrence, occurrenceEnd, allDay, self))
+		}
+	}
+	return res, nil
+}
+
+// icsOccurrences returns the starts of the recurring event between after and before, using RRULE, RDATE and EXDATE.
+func icsOccurrences(component *icsComponent, start time.Time, after time.Time, before time.Time) ([]time.Time, error) {
+	option, err := rrule.StrToROptionInLocation(component.property("RRULE").value, start.Location())
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	option.Dtstart = start
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	set := &rrule.Set{}
+	set.RRule(rule)
+	for _, prop := range component.properties {
+		if prop.name != "EXDATE" && prop.name != "RDATE" {
+			continue
+		}
+		for _, value := range strings.Split(prop.value, ",") {
+			t, _, err := icsTime(&icsProperty{name: prop.name, params: prop.params, value: value})
+			if err != nil {
+				return nil, err
+			}
+			if prop.name == "EXDATE" {
+				set.ExDate(t)
+			} else {
+				set.RDate(t)
+			}
+		}
+	}
+	return set.Between(after, before, true), nil
+}
+
+func recurrenceKey(uid string, start time.Time) string {
+	return uid + "/" + start.UTC().Format(time.RFC3339)
+}
+
+// overlaps returns true if the event is (at least partially) between from and to. Zero length events are included if
+// they start in the range.
+func overlaps(start time.Time, end time.Time, from time.Time, to time.Time) bool {
+	if !start.Before(to) {
+		return false
+	}
+	return end.After(from) || (!end.After(start) && !start.Before(from))
+}
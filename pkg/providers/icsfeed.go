@@ -0,0 +1,112 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// ICSFeed is an iCalendar subscription (public or secret ICS URL).
+type ICSFeed struct {
+	url    string
+	client *http.Client
+	// cacheDir is the directory of the downloaded feed.
+	cacheDir string
+	refresh  time.Duration
+}
+
+// icsFeedCache is the last downloaded version of the feed.
+type icsFeedCache struct {
+	Fetched      time.Time `json:"fetched"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"last-modified"`
+	Content      string    `json:"content"`
+}
+
+// NewICSFeed creates the source of the feed. The feed is cached in cacheDir and downloaded again after refresh.
+func NewICSFeed(url string, cacheDir string, refresh time.Duration) ICSFeed {
+	return ICSFeed{
+		url:      url,
+		client:   &http.Client{Timeout: time.Minute},
+		cacheDir: cacheDir,
+		refresh:  refresh,
+	}
+}
+
+// Name is based on the hash of the URL, secret URLs shouldn't appear in the logs and the file names.
+func (f ICSFeed) Name() string {
+	hash := sha256.Sum256([]byte(f.url))
+	return "ics-" + hex.EncodeToString(hash[:6])
+}
+
+func (f ICSFeed) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	content, err := f.download(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := icsCalendarEvents(content, from, to, "")
+	if err != nil {
+		return nil, errs.Errorf("invalid iCalendar feed %s: %v", f.Name(), err)
+	}
+	return events, nil
+}
+
+// download returns the content of the feed. The feed is downloaded at most once per --ics-refresh, and only if it's
+// changed since the previous download.
+func (f ICSFeed) download(ctx context.Context) (string, error) {
+	file := filepath.Join(f.cacheDir, f.Name()+".json")
+	cached := icsFeedCache{}
+	if !osutil.ReadCache(file, &cached) {
+		// a partially decoded cache shouldn't be used for the conditional request
+		cached = icsFeedCache{}
+	}
+	if time.Since(cached.Fetched) < f.refresh {
+		return cached.Content, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return "", errs.Errorf("invalid iCalendar feed URL %s", f.Name())
+	}
+	if cached.Content != "" {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// the error contains the URL
+		return "", errs.Errorf("couldn't download iCalendar feed %s", f.Name())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+	case http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", errs.Errorf("couldn't download iCalendar feed %s", f.Name())
+		}
+		cached.Content = string(body)
+		cached.ETag = resp.Header.Get("ETag")
+		cached.LastModified = resp.Header.Get("Last-Modified")
+	default:
+		return "", errs.Errorf("iCalendar feed %s returned %s", f.Name(), resp.Status)
+	}
+	cached.Fetched = time.Now()
+	if err := osutil.WriteJSON(file, cached); err != nil {
+		log.Printf("iCalendar feed couldn't be cached: %v", err)
+	}
+	return cached.Content, nil
+}
@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestICSFeedRecoversFromCorruptCache(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1\r\nSUMMARY:Standup\r\n" +
+		"DTSTART:20210601T100000Z\r\nDTEND:20210601T101500Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("conditional request shouldn't be sent with a corrupt cache")
+		}
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	source := NewICSFeed(server.URL, cacheDir, time.Hour)
+	corrupt := `{"fetched":"2999-01-01T00:00:00Z","etag":"x","content":"BEGIN:VCAL`
+	if err := ioutil.WriteFile(filepath.Join(cacheDir, source.Name()+".json"), []byte(corrupt), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	events, err := source.Events(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || len(events) != 1 || events[0].Summary != "Standup" {
+		t.Fatalf("the feed should be downloaded again, got %d requests and %v", requests, events)
+	}
+}
@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/zeebo/errs/v2"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OutlookConfig is an [[outlook]] section of the config file.
+type OutlookConfig struct {
+	// Name identifies the account in the cache and the token file.
+	Name string `toml:"name"`
+	// ClientID is the id of the public client app registration (mobile and desktop application).
+	ClientID string `toml:"client-id"`
+	// Tenant is the directory of the account (common, organizations or the tenant id).
+	Tenant string `toml:"tenant"`
+	// Calendar is the id of the calendar, the default calendar is used if it's empty.
+	Calendar string `toml:"calendar"`
+}
+
+const graphURL = "https://graph.microsoft.com/v1.0"
+
+// OAuthConfig returns the OAuth2 configuration of the Microsoft identity platform.
+func (c OutlookConfig) OAuthConfig() *oauth2.Config {
+	tenant := c.Tenant
+	if tenant == "" {
+		tenant = "common"
+	}
+	return &oauth2.Config{
+		ClientID: c.ClientID,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   "https://login.microsoftonline.com/" + tenant + "/oauth2/v2.0/authorize",
+			TokenURL:  "https://login.microsoftonline.com/" + tenant + "/oauth2/v2.0/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		Scopes: []string{"offline_access", "Calendars.Read"},
+	}
+}
+
+// DeviceCodeURL is the device authorization endpoint of the tenant.
+func (c OutlookConfig) DeviceCodeURL() string {
+	return strings.Replace(c.OAuthConfig().Endpoint.TokenURL, "/token", "/devicecode", 1)
+}
+
+func (c OutlookConfig) tokenFile() string {
+	return fmt.Sprintf("outlook-%s-token.json", url.PathEscape(c.Name))
+}
+
+// Outlook reads the events of a Microsoft 365 / Outlook calendar with the Graph API.
+type Outlook struct {
+	config OutlookConfig
+	client *http.Client
+}
+
+// NewOutlook creates the Graph API client of the calendar, using the token saved by WriteOutlookToken.
+func NewOutlook(ctx context.Context, authOpts auth.Options, config OutlookConfig) (Outlook, error) {
+	if config.Name == "" || config.ClientID == "" {
+		return Outlook{}, errs.Errorf("name and client-id of the outlook calendars are required")
+	}
+	content, err := auth.ReadTokenFile(authOpts, config.tokenFile())
+	if err != nil {
+		return Outlook{}, errs.Errorf("token of outlook calendar %q is missing, please run setup-outlook --name %s: %v", config.Name, config.Name, err)
+	}
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(content, token); err != nil {
+		return Outlook{}, errs.Wrap(err)
+	}
+	tokenSource := auth.NewPersistingTokenSource(config.OAuthConfig().TokenSource(ctx, token), token, func(token *oauth2.Token) error {
+		return WriteOutlookToken(authOpts, config, token)
+	})
+	return Outlook{
+		config: config,
+		client: oauth2.NewClient(ctx, tokenSource),
+	}, nil
+}
+
+// WriteOutlookToken saves the token of the calendar to the token store of the config dir.
+func WriteOutlookToken(authOpts auth.Options, config OutlookConfig, token *oauth2.Token) error {
+	content, err := json.Marshal(token)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	return auth.WriteTokenFile(authOpts, config.tokenFile(), content)
+}
+
+func (o Outlook) Name() string {
+	return "outlook-" + o.config.Name
+}
+
+// graphEvent is the event resource of the Graph API (only the used fields).
+type graphEvent struct {
+	ID             string         `json:"id"`
+	ICalUID        string         `json:"iCalUId"`
+	Subject        string         `json:"subject"`
+	BodyPreview    string         `json:"bodyPreview"`
+	Start          graphDateTime  `json:"start"`
+	End            graphDateTime  `json:"end"`
+	IsAllDay       bool           `json:"isAllDay"`
+	IsCancelled    bool           `json:"isCancelled"`
+	ShowAs         string         `json:"showAs"`
+	WebLink        string         `json:"webLink"`
+	Location       graphLocation  `json:"location"`
+	ResponseStatus graphResponse  `json:"responseStatus"`
+	Organizer      graphRecipient `json:"organizer"`
+	Attendees      []struct {
+		graphRecipient
+		Type   string        `json:"type"`
+		Status graphResponse `json:"status"`
+	} `json:"attendees"`
+	OnlineMeeting *struct {
+		JoinURL string `json:"joinUrl"`
+	} `json:"onlineMeeting"`
+}
+
+type graphDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphLocation struct {
+	DisplayName string `json:"displayName"`
+}
+
+type graphResponse struct {
+	Response string `json:"response"`
+}
+
+type graphRecipient struct {
+	EmailAddress struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"emailAddress"`
+}
+
+func (o Outlook) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	endpoint := graphURL + "/me/calendarView"
+	if o.config.Calendar != "" {
+		endpoint = graphURL + "/me/calendars/" + url.PathEscape(o.config.Calendar) + "/calendarView"
+	}
+	endpoint += "?" + url.Values{
+		"startDateTime": {from.UTC().Format(time.RFC3339)},
+		"endDateTime":   {to.UTC().Format(time.RFC3339)},
+		"$top":          {"100"},
+	}.Encode()
+
+	var res []*calendar.Event
+	for endpoint != "" {
+		var page struct {
+			Value    []graphEvent `json:"value"`
+			NextLink string       `json:"@odata.nextLink"`
+		}
+		if err := o.get(ctx, endpoint, &page); err != nil {
+			return nil, err
+		}
+		for _, event := range page.Value {
+			res = append(res, event.convert())
+		}
+		endpoint = page.NextLink
+	}
+	return res, nil
+}
+
+func (o Outlook) get(ctx context.Context, endpoint string, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	req.Header.Set("Prefer", `outlook.timezone="UTC"`)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return errs.Errorf("outlook calendar %q returned %s", o.config.Name, resp.Status)
+	}
+	return errs.Wrap(json.NewDecoder(resp.Body).Decode(response))
+}
+
+// convert converts the Graph event to the structure of the Google Calendar API.
+func (g graphEvent) convert() *calendar.Event {
+	event := &calendar.Event{
+		Id:          g.ID,
+		ICalUID:     g.ICalUID,
+		Summary:     g.Subject,
+		Description: g.BodyPreview,
+		Location:    g.Location.DisplayName,
+		HtmlLink:    g.WebLink,
+		Status:      "confirmed",
+		Start:       g.Start.convert(g.IsAllDay),
+		End:         g.End.convert(g.IsAllDay),
+		Organizer: &calendar.EventOrganizer{
+			Email:       g.Organizer.EmailAddress.Address,
+			DisplayName: g.Organizer.EmailAddress.Name,
+		},
+	}
+	if g.IsCancelled {
+		event.Status = "cancelled"
+	}
+	if g.ShowAs == "oof" {
+		event.EventType = "outOfOffice"
+	}
+	if g.OnlineMeeting != nil && g.OnlineMeeting.JoinURL != "" {
+		event.ConferenceData = &calendar.ConferenceData{
+			EntryPoints: []*calendar.EntryPoint{{EntryPointType: "video", Uri: g.OnlineMeeting.JoinURL}},
+		}
+	}
+	// the current user is not listed in the attendees, only the own response is available
+	event.Attendees = append(event.Attendees, &calendar.EventAttendee{
+		Self:           true,
+		ResponseStatus: graphResponseStatus(g.ResponseStatus.Response),
+	})
+	for _, attendee := range g.Attendees {
+		event.Attendees = append(event.Attendees, &calendar.EventAttendee{
+			Email:          attendee.EmailAddress.Address,
+			DisplayName:    attendee.EmailAddress.Name,
+			Resource:       attendee.Type == "resource",
+			ResponseStatus: graphResponseStatus(attendee.Status.Response),
+		})
+	}
+	return event
+}
+
+// convert returns the time of the event (in UTC, requested with the Prefer header).
+func (g graphDateTime) convert(allDay bool) *calendar.EventDateTime {
+	t, err := time.Parse("2006-01-02T15:04:05.9999999", g.DateTime)
+	if err != nil {
+		return &calendar.EventDateTime{}
+	}
+	if allDay {
+		return &calendar.EventDateTime{Date: t.Format("2006-01-02")}
+	}
+	return &calendar.EventDateTime{DateTime: t.Format(time.RFC3339)}
+}
+
+// graphResponseStatus converts the responses of the Graph API to the response statuses of the Google Calendar API.
+func graphResponseStatus(response string) string {
+	switch response {
+	case "accepted", "organizer":
+		return "accepted"
+	case "declined":
+		return "declined"
+	case "tentativelyAccepted":
+		return "tentative"
+	default:
+		return "needsAction"
+	}
+}
@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"os/exec"
+	"time"
+)
+
+// Plugin gets the events from an external executable. The command gets a pluginRequest as JSON on the stdin
+// and prints a pluginResponse as JSON to the stdout, like:
+//
+//	{"from": "2021-06-01T00:00:00+02:00", "to": "2021-06-02T00:00:00+02:00"}
+//	{"events": [{"id": "1", "summary": "Standup", "start": "2021-06-01T09:30:00+02:00", "end": "2021-06-01T09:45:00+02:00"}]}
+type Plugin struct {
+	Command string
+}
+
+type pluginRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type pluginResponse struct {
+	Events []pluginEvent `json:"events"`
+}
+
+// pluginEvent is an event returned by a plugin. Start and end are RFC3339 times, or dates (2006-01-02) of all-day
+// events. Status and response are the values of the Google Calendar API (confirmed, tentative, cancelled and
+// accepted, declined, tentative, needsAction).
+type pluginEvent struct {
+	ID          string           `json:"id"`
+	Summary     string           `json:"summary"`
+	Start       string           `json:"start"`
+	End         string           `json:"end"`
+	AllDay      bool             `json:"all_day"`
+	Location    string           `json:"location"`
+	Description string           `json:"description"`
+	URL         string           `json:"url"`
+	Status      string           `json:"status"`
+	Attendees   []pluginAttendee `json:"attendees"`
+}
+
+type pluginAttendee struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Response string `json:"response"`
+	Self     bool   `json:"self"`
+}
+
+// Name is based on the hash of the command, which can contain secrets.
+func (p Plugin) Name() string {
+	hash := sha256.Sum256([]byte(p.Command))
+	return "plugin-" + hex.EncodeToString(hash[:6])
+}
+
+func (p Plugin) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	request, err := json.Marshal(pluginRequest{From: from, To: to})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.Command)
+	cmd.Stdin = bytes.NewReader(request)
+	out, err := osutil.CommandOutput(cmd)
+	if err != nil {
+		return nil, errs.Errorf("plugin %q is failed: %v", p.Command, err)
+	}
+	response := pluginResponse{}
+	if err := json.Unmarshal(out, &response); err != nil {
+		return nil, errs.Errorf("invalid response of plugin %q: %v", p.Command, err)
+	}
+
+	var res []*calendar.Event
+	for _, event := range response.Events {
+		converted, err := event.convert()
+		if err != nil {
+			return nil, errs.Errorf("invalid response of plugin %q: %v", p.Command, err)
+		}
+		res = append(res, converted)
+	}
+	return res, nil
+}
+
+// convert converts the plugin event to the structure of the Google Calendar API. Events with date-only start are
+// all-day events, even if all_day is not set.
+func (e pluginEvent) convert() (*calendar.Event, error) {
+	allDay := e.AllDay || len(e.Start) == len(pluginDateLayout)
+	start, err := pluginTime(e.Start, allDay)
+	if err != nil {
+		return nil, errs.Errorf("invalid start of event %q: %v", e.ID, err)
+	}
+	end, err := pluginTime(e.End, allDay)
+	if err != nil {
+		return nil, errs.Errorf("invalid end of event %q: %v", e.ID, err)
+	}
+	event := &calendar.Event{
+		Id:          e.ID,
+		Summary:     e.Summary,
+		Location:    e.Location,
+		Description: e.Description,
+		HtmlLink:    e.URL,
+		Status:      e.Status,
+		Start:       start,
+		End:         end,
+	}
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	for _, attendee := range e.Attendees {
+		event.Attendees = append(event.Attendees, &calendar.EventAttendee{
+			Email:          attendee.Email,
+			DisplayName:    attendee.Name,
+			ResponseStatus: attendee.Response,
+			Self:           attendee.Self,
+		})
+	}
+	return event, nil
+}
+
+const pluginDateLayout = "2006-01-02"
+
+// pluginTime validates the time (or the date of all-day events) returned by a plugin.
+func pluginTime(value string, allDay bool) (*calendar.EventDateTime, error) {
+	if allDay {
+		if _, err := time.Parse(pluginDateLayout, value); err != nil {
+			return nil, errs.Errorf("%q is not a date (2006-01-02)", value)
+		}
+		return &calendar.EventDateTime{Date: value}, nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return nil, errs.Errorf("%q is not an RFC3339 time", value)
+	}
+	return &calendar.EventDateTime{DateTime: value}, nil
+}
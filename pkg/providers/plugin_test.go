@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginEventConvert(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  pluginEvent
+		allDay bool
+		start  time.Time
+		err    string
+	}{
+		{
+			name:  "timed",
+			event: pluginEvent{ID: "1", Start: "2021-06-01T09:30:00Z", End: "2021-06-01T09:45:00Z"},
+			start: time.Date(2021, 6, 1, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:   "all-day",
+			event:  pluginEvent{ID: "1", Start: "2021-06-01", End: "2021-06-02", AllDay: true},
+			allDay: true,
+			start:  time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:   "date without all_day",
+			event:  pluginEvent{ID: "1", Start: "2021-06-01", End: "2021-06-02"},
+			allDay: true,
+			start:  time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:  "invalid start",
+			event: pluginEvent{ID: "1", Start: "tomorrow", End: "2021-06-01T09:45:00Z"},
+			err:   `invalid start of event "1"`,
+		},
+		{
+			name:  "missing end",
+			event: pluginEvent{ID: "1", Start: "2021-06-01T09:30:00Z"},
+			err:   `invalid end of event "1"`,
+		},
+		{
+			name:  "time in all-day event",
+			event: pluginEvent{ID: "1", Start: "2021-06-01T09:30:00Z", End: "2021-06-01T09:45:00Z", AllDay: true},
+			err:   `invalid start of event "1"`,
+		},
+		{
+			name:  "date end of timed event",
+			event: pluginEvent{ID: "1", Start: "2021-06-01T09:30:00Z", End: "2021-06-02"},
+			err:   `invalid end of event "1"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := tc.event.convert()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			event := render.NewEvent(raw)
+			if event.AllDay != tc.allDay || !event.Start.Equal(tc.start) {
+				t.Fatalf("expected %s (all-day: %v), got %s (all-day: %v)", tc.start, tc.allDay, event.Start, event.AllDay)
+			}
+		})
+	}
+}
+
+func TestPluginEvents(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	valid := Plugin{Command: `cat >/dev/null; echo '{"events": [{"id": "1", "summary": "Standup", "start": "2021-06-01T09:30:00Z", "end": "2021-06-01T09:45:00Z"}]}'`}
+	events, err := valid.Events(context.Background(), from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Summary != "Standup" || events[0].Status != "confirmed" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	invalid := Plugin{Command: `cat >/dev/null; echo '{"events": [{"id": "1", "start": "06/01/2021", "end": "06/02/2021"}]}'`}
+	if _, err := invalid.Events(context.Background(), from, from.AddDate(0, 0, 1)); err == nil || !strings.Contains(err.Error(), "plugin") {
+		t.Fatalf("invalid times should be reported with the plugin, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Package providers fetches the events of the calendars: Google, CalDAV, Microsoft 365 (Graph API), iCalendar feeds,
+// vdir directories and external plugins. The events of all the providers are converted to the structure of the Google
+// Calendar API.
+package providers
+
+import (
+	"context"
+	"github.com/BurntSushi/toml"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"os"
+	"time"
+)
+
+// Source is one calendar of a provider (Google, CalDAV...).
+type Source interface {
+	// Name identifies the calendar in the cache files and in the events.
+	Name() string
+	// Events returns the events between from and to, converted to the structure of the Google Calendar API.
+	Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error)
+}
+
+// Config is the configuration of the non-Google calendars, from the sections of the config file.
+type Config struct {
+	CalDAV  []CalDAVConfig  `toml:"caldav"`
+	Outlook []OutlookConfig `toml:"outlook"`
+}
+
+// ConfigSections are the sections of the config file which are not flags.
+var ConfigSections = []string{"caldav", "outlook"}
+
+// ReadConfig reads the provider sections of the config file. Missing file means no configured providers.
+func ReadConfig(file string) (Config, error) {
+	config := Config{}
+	if file == "" {
+		return config, nil
+	}
+	_, err := toml.DecodeFile(file, &config)
+	if err != nil && !os.IsNotExist(err) {
+		return config, errs.Errorf("couldn't read providers from %s: %v", file, err)
+	}
+	return config, nil
+}
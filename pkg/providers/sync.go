@@ -0,0 +1,117 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/internal/osutil"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// syncState is the locally maintained copy of a calendar, updated with incremental syncs.
+type syncState struct {
+	Token  string                     `json:"token"`
+	From   time.Time                  `json:"from"`
+	To     time.Time                  `json:"to"`
+	Events map[string]*calendar.Event `json:"events"`
+}
+
+func syncStateFile(dir string, calendarID string) string {
+	return filepath.Join(dir, fmt.Sprintf("sync-%s.json", url.PathEscape(calendarID)))
+}
+
+// syncCalendar returns the events of the calendar between from and to. Only the changes since the previous call are
+// downloaded, the full calendar is fetched only if there is no usable sync token.
+func syncCalendar(ctx context.Context, cal Google, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	if len(cal.SharedPropertyFilter) > 0 || len(cal.PrivatePropertyFilter) > 0 {
+		return nil, errs.Errorf("extended property filters can't be used together with incremental sync")
+	}
+	file := syncStateFile(cal.SyncDir, cal.Name())
+
+	state := &syncState{}
+	if !osutil.ReadCache(file, state) || state.Token == "" || from.Before(state.From) || to.After(state.To) {
+		state = nil
+	}
+	if state != nil && state.Events == nil {
+		state.Events = map[string]*calendar.Event{}
+	}
+
+	if state != nil {
+		err := syncChanges(ctx, cal.Service, cal.ID, state)
+		if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == http.StatusGone {
+			log.Printf("sync token of %s is expired, fetching all the events", cal.Name())
+			state = nil
+		} else if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	if state == nil {
+		// the window is wider than requested, to keep the token usable when the requested window moves
+		state = &syncState{
+			From:   from.AddDate(0, 0, -1),
+			To:     to.AddDate(0, 0, 7),
+			Events: map[string]*calendar.Event{},
+		}
+		err := syncChanges(ctx, cal.Service, cal.ID, state)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	if err := osutil.WriteJSON(file, state); err != nil {
+		log.Printf("sync state couldn't be saved: %v", err)
+	}
+
+	var events []*calendar.Event
+	for _, raw := range state.Events {
+		event := render.NewEvent(raw)
+		end := event.End
+		if end.IsZero() {
+			end = event.Start
+		}
+		if event.Start.Before(to) && end.After(from) {
+			events = append(events, raw)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+// syncChanges downloads all the pages of changes and applies them to the state. Without token, all events of the state
+// window are downloaded.
+func syncChanges(ctx context.Context, service *calendar.Service, id string, state *syncState) error {
+	call := service.Events.List(id).SingleEvents(true)
+	if state.Token != "" {
+		call = call.SyncToken(state.Token)
+	} else {
+		call = call.TimeMin(state.From.Format(time.RFC3339)).TimeMax(state.To.Format(time.RFC3339))
+	}
+	var token string
+	err := call.Pages(ctx, func(events *calendar.Events) error {
+		for _, event := range events.Items {
+			if event.Status == "cancelled" {
+				delete(state.Events, event.Id)
+				continue
+			}
+			state.Events[event.Id] = event
+		}
+		token = events.NextSyncToken
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	state.Token = token
+	return nil
+}
@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"context"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSyncCalendarRecoversFromCorruptState(t *testing.T) {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.URL.Query().Get("syncToken"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[{"id":"1","summary":"Standup","start":{"dateTime":"2021-06-01T10:00:00Z"},"end":{"dateTime":"2021-06-01T10:15:00Z"}}],"nextSyncToken":"next"}`))
+	}))
+	defer server.Close()
+	service, err := calendar.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cal := Google{Service: service, ID: "primary", SyncDir: t.TempDir()}
+	file := syncStateFile(cal.SyncDir, cal.Name())
+	if err := ioutil.WriteFile(file, []byte(`{"token":"old","from":"2021-05-3`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := syncCalendar(context.Background(), cal, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Summary != "Standup" {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if len(tokens) != 1 || tokens[0] != "" {
+		t.Fatalf("corrupt state should be handled as missing, full sync is expected, got tokens %q", tokens)
+	}
+
+	// the rewritten state is used by the next sync
+	if _, err := syncCalendar(context.Background(), cal, from, from.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[1] != "next" {
+		t.Fatalf("expected incremental sync, got tokens %q", tokens)
+	}
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/zeebo/errs/v2"
+	"google.golang.org/api/calendar/v3"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VDir reads the events from a local vdir (one .ics file per event), as synchronized by vdirsyncer.
+// Subdirectories (one per calendar, like in the khal layout) are read, too.
+type VDir struct {
+	Path string
+}
+
+func (v VDir) Name() string {
+	hash := sha256.Sum256([]byte(v.Path))
+	return "vdir-" + hex.EncodeToString(hash[:6])
+}
+
+func (v VDir) Events(ctx context.Context, from time.Time, to time.Time) ([]*calendar.Event, error) {
+	files, err := icsFiles(v.Path)
+	if err != nil {
+		return nil, err
+	}
+	var res []*calendar.Event
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		events, err := icsCalendarEvents(string(content), from, to, "")
+		if err != nil {
+			// one broken file shouldn't hide the whole calendar
+			log.Printf("skipping %s: %v", file, err)
+			continue
+		}
+		res = append(res, events...)
+	}
+	return res, nil
+}
+
+// icsFiles returns the .ics files of the directory, recursively.
+func icsFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".ics") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Errorf("couldn't read vdir %s: %v", dir, err)
+	}
+	return files, nil
+}
+
+// VDirFingerprint summarizes the number, size and modification time of the .ics files, to detect the changes.
+func VDirFingerprint(dirs []string) string {
+	var count, size int64
+	var modified time.Time
+	for _, dir := range dirs {
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(strings.ToLower(info.Name()), ".ics") {
+				return nil
+			}
+			count++
+			size += info.Size()
+			if info.ModTime().After(modified) {
+				modified = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return fmt.Sprintf("%d/%d/%d", count, size, modified.UnixNano())
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"fmt"
+	"google.golang.org/api/calendar/v3"
+	"strings"
+	"time"
+)
+
+// Event is a calendar event with the parsed start and end times.
+type Event struct {
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+	// Calendar is the identifier of the calendar of the event.
+	Calendar string
+	// Stale is true if the event is served from the cache because the API is not available.
+	Stale bool
+	Raw   *calendar.Event
+}
+
+// NewEvent parses the start and end of the calendar event. All-day events only have a date, they start at local midnight.
+func NewEvent(raw *calendar.Event) Event {
+	event := Event{
+		Start: parseEventTime(raw.Start),
+		Raw:   raw,
+	}
+	if raw.End != nil {
+		event.End = parseEventTime(raw.End)
+	}
+	event.AllDay = raw.Start.DateTime == ""
+	return event
+}
+
+func parseEventTime(t *calendar.EventDateTime) time.Time {
+	if t.DateTime == "" {
+		res, _ := time.ParseInLocation("2006-01-02", t.Date, time.Local)
+		return res
+	}
+	res, _ := time.Parse(time.RFC3339, t.DateTime)
+	return res.Local()
+}
+
+// Declined returns true if the current user declined the invitation.
+func (e Event) Declined() bool {
+	return e.ResponseStatus() == "declined"
+}
+
+// ResponseStatus returns the response of the current user to the invitation, or empty string if not invited.
+func (e Event) ResponseStatus() string {
+	for _, attendee := range e.Raw.Attendees {
+		if attendee.Self {
+			return attendee.ResponseStatus
+		}
+	}
+	return ""
+}
+
+// OutOfOffice returns true for the out of office events of Google Calendar.
+func (e Event) OutOfOffice() bool {
+	return e.Raw.EventType == "outOfOffice"
+}
+
+// InProgress returns true if the event is running at the given time.
+func (e Event) InProgress(now time.Time) bool {
+	return !now.Before(e.Start) && now.Before(e.End)
+}
+
+// Progress returns the elapsed ratio (0-1) of the event.
+func (e Event) Progress(now time.Time) float64 {
+	if !e.End.After(e.Start) {
+		return 0
+	}
+	return float64(now.Sub(e.Start)) / float64(e.End.Sub(e.Start))
+}
+
+// Finished returns true if the event is already over at the given time.
+func (e Event) Finished(now time.Time) bool {
+	end := e.End
+	if end.IsZero() {
+		end = e.Start
+	}
+	return !now.Before(end)
+}
+
+// DeclinedByOthers returns the number of other (human) attendees and how many of them declined.
+func (e Event) DeclinedByOthers() (declined int, all int) {
+	for _, attendee := range e.Raw.Attendees {
+		if attendee.Self || attendee.Resource {
+			continue
+		}
+		all++
+		if attendee.ResponseStatus == "declined" {
+			declined++
+		}
+	}
+	return declined, all
+}
+
+// recurringSlot identifies one occurrence of a recurring event. Returns empty string for non-recurring events.
+func (e Event) recurringSlot() string {
+	series := e.Raw.RecurringEventId
+	if series == "" && len(e.Raw.Recurrence) > 0 {
+		series = e.Raw.Id
+	}
+	if series == "" {
+		return ""
+	}
+	original := e.Raw.OriginalStartTime
+	if original == nil {
+		original = e.Raw.Start
+	}
+	return series + "/" + original.DateTime + original.Date
+}
+
+// CollapseRecurring keeps only one event per recurring slot, preferring the exception instance over the base occurrence.
+func CollapseRecurring(events []Event) []Event {
+	var res []Event
+	slots := map[string]int{}
+	for _, event := range events {
+		slot := event.recurringSlot()
+		if slot == "" {
+			res = append(res, event)
+			continue
+		}
+		if idx, found := slots[slot]; found {
+			if res[idx].Raw.RecurringEventId == "" && event.Raw.RecurringEventId != "" {
+				res[idx] = event
+			}
+			continue
+		}
+		slots[slot] = len(res)
+		res = append(res, event)
+	}
+	return res
+}
+
+// OrganizerInitial returns the first letter of the name (or email) of the organizer.
+func (e Event) OrganizerInitial() string {
+	if e.Raw.Organizer == nil {
+		return ""
+	}
+	name := strings.TrimSpace(e.Raw.Organizer.DisplayName)
+	if name == "" {
+		name = e.Raw.Organizer.Email
+	}
+	for _, r := range name {
+		return strings.ToUpper(string(r))
+	}
+	return ""
+}
+
+// AttendeeNames returns the display names (or emails) of the attendees, except the current user and the rooms.
+func (e Event) AttendeeNames() []string {
+	var names []string
+	for _, attendee := range e.Raw.Attendees {
+		if attendee.Self || attendee.Resource {
+			continue
+		}
+		name := attendee.DisplayName
+		if name == "" {
+			name = attendee.Email
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Guests returns the names of the other attendees, showing only the first max names.
+func (e Event) Guests(max int) string {
+	names := e.AttendeeNames()
+	if max > 0 && len(names) > max {
+		return fmt.Sprintf("%s +%d more", strings.Join(names[:max], ", "), len(names)-max)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Attending returns true if the current user is a direct attendee of the event.
+func (e Event) Attending() bool {
+	for _, attendee := range e.Raw.Attendees {
+		if attendee.Self {
+			return true
+		}
+	}
+	return false
+}
+
+// DedupeByICalUID keeps only one copy of the same event (occurrence), preferring the copy where the user is a direct attendee.
+func DedupeByICalUID(events []Event) []Event {
+	var res []Event
+	seen := map[string]int{}
+	for _, event := range events {
+		if event.Raw.ICalUID == "" {
+			res = append(res, event)
+			continue
+		}
+		original := event.Raw.OriginalStartTime
+		if original == nil {
+			original = event.Raw.Start
+		}
+		key := event.Raw.ICalUID + "/" + original.DateTime + original.Date
+		if idx, found := seen[key]; found {
+			if !res[idx].Attending() && event.Attending() {
+				res[idx] = event
+			}
+			continue
+		}
+		seen[key] = len(res)
+		res = append(res, event)
+	}
+	return res
+}
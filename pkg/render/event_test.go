@@ -0,0 +1,143 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"testing"
+	"time"
+)
+
+func TestCollapseRecurring(t *testing.T) {
+	recurring := func(id string, series string, summary string, start time.Time) Event {
+		event := testEvent(summary, start, start.Add(30*time.Minute))
+		event.Raw.Id = id
+		event.Raw.RecurringEventId = series
+		if series == "" {
+			event.Raw.Recurrence = []string{"RRULE:FREQ=DAILY"}
+		} else {
+			event.Raw.OriginalStartTime = &calendar.EventDateTime{DateTime: clock(10, 0).Format(time.RFC3339)}
+		}
+		return event
+	}
+	base := recurring("standup", "", "Standup", clock(10, 0))
+	exception := recurring("standup_20210601T080000Z", "standup", "Standup (moved)", clock(11, 0))
+	other := testEvent("Review", clock(12, 0), clock(13, 0))
+
+	tests := []struct {
+		name     string
+		events   []Event
+		expected []string
+	}{
+		{name: "base first", events: []Event{base, exception, other}, expected: []string{"Standup (moved)", "Review"}},
+		{name: "exception first", events: []Event{exception, base, other}, expected: []string{"Standup (moved)", "Review"}},
+		{name: "only the base", events: []Event{base, other}, expected: []string{"Standup", "Review"}},
+		{name: "not recurring", events: []Event{other, other}, expected: []string{"Review", "Review"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, event := range CollapseRecurring(tc.events) {
+				got = append(got, event.Raw.Summary)
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOrganizerInitial(t *testing.T) {
+	tests := []struct {
+		name      string
+		organizer *calendar.EventOrganizer
+		expected  string
+	}{
+		{name: "display name", organizer: &calendar.EventOrganizer{DisplayName: "dave", Email: "bob@example.com"}, expected: "D"},
+		{name: "display name with spaces", organizer: &calendar.EventOrganizer{DisplayName: "  Éva Kiss"}, expected: "É"},
+		{name: "email fallback", organizer: &calendar.EventOrganizer{Email: "bob@example.com"}, expected: "B"},
+		{name: "blank display name", organizer: &calendar.EventOrganizer{DisplayName: " ", Email: "carol@example.com"}, expected: "C"},
+		{name: "no organizer info", organizer: &calendar.EventOrganizer{}},
+		{name: "no organizer"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event := testEvent("Review", clock(10, 0), clock(11, 0))
+			event.Raw.Organizer = tc.organizer
+			if got := event.OrganizerInitial(); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+
+			opts := defaultOptions()
+			opts.OrganizerInitial = true
+			text, err := headline(event, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := "10:00 Review"
+			if tc.expected != "" {
+				expected = "[" + tc.expected + "] " + expected
+			}
+			if text != expected {
+				t.Fatalf("expected headline %q, got %q", expected, text)
+			}
+		})
+	}
+}
+
+func TestDedupeByICalUID(t *testing.T) {
+	copyOf := func(summary string, cal string, uid string, attending bool) Event {
+		event := testEvent(summary, clock(10, 0), clock(11, 0))
+		event.Calendar = cal
+		event.Raw.ICalUID = uid
+		if attending {
+			event.Raw.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "accepted"}}
+		}
+		return event
+	}
+	tests := []struct {
+		name     string
+		events   []Event
+		expected []string
+	}{
+		{
+			name:     "shared event, attending in the second calendar",
+			events:   []Event{copyOf("Review", "team", "uid-1", false), copyOf("Review", "me", "uid-1", true)},
+			expected: []string{"me"},
+		},
+		{
+			name:     "shared event, attending in the first calendar",
+			events:   []Event{copyOf("Review", "me", "uid-1", true), copyOf("Review", "team", "uid-1", false)},
+			expected: []string{"me"},
+		},
+		{
+			name:     "different events",
+			events:   []Event{copyOf("Review", "me", "uid-1", true), copyOf("Review", "team", "uid-2", false)},
+			expected: []string{"me", "team"},
+		},
+		{
+			name:     "without iCalUID",
+			events:   []Event{copyOf("Review", "me", "", true), copyOf("Review", "team", "", false)},
+			expected: []string{"me", "team"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, event := range DedupeByICalUID(tc.events) {
+				got = append(got, event.Calendar)
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zeebo/errs/v2"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Colors are the colors of the classes of the bar item, for the status bars which don't support CSS.
+type Colors map[string]string
+
+// ParseColors parses the class=color definitions (like in-meeting=#ff5555).
+func ParseColors(specs []string) (Colors, error) {
+	colors := Colors{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errs.Errorf("invalid color %q, use class=color", spec)
+		}
+		colors[parts[0]] = parts[1]
+	}
+	return colors, nil
+}
+
+// Color returns the color of the first class of the item which has a color, or empty string.
+func (c Colors) Color(item BarItem) string {
+	for _, class := range item.Class {
+		if color, found := c[class]; found {
+			return color
+		}
+	}
+	return ""
+}
+
+// Polybar formats the item as one line with polybar formatting tags: the text is colored by the classes, and the
+// click command is executed on left click.
+func Polybar(item BarItem, colors Colors, click string) string {
+	// % starts the formatting tags of polybar
+	text := strings.ReplaceAll(oneLine(item.Text), "%", "%%")
+	if color := colors.Color(item); color != "" {
+		text = "%{F" + color + "}" + text + "%{F-}"
+	}
+	if item.Urgent {
+		text = "%{+u}" + text + "%{-u}"
+	}
+	if click != "" {
+		text = "%{A1:" + strings.ReplaceAll(click, ":", `\:`) + ":}" + text + "%{A}"
+	}
+	return text
+}
+
+// oneLine joins the lines of the text (of the two-line mode) for the single line status bars.
+func oneLine(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\n", " "))
+}
+
+// I3Blocks formats the item as the full text, the short text and the color lines of i3blocks. The short text is
+// truncated to shortLength characters (when i3bar has no space for the full text).
+func I3Blocks(item BarItem, colors Colors, shortLength int) string {
+	text := oneLine(item.Text)
+	return text + "\n" + truncateRunes(text, shortLength) + "\n" + colors.Color(item)
+}
+
+// truncateRunes shortens the text to max characters (0 means no limit). The text is cut at the last word boundary
+// if it's not too far, and the ellipsis is added.
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if max <= 0 || len(runes) <= max {
+		return text
+	}
+	cut := string(runes[:max-1])
+	if space := strings.LastIndex(cut, " "); space > len(cut)/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
+
+// Xmobar formats the item with xmobar markup: the text is colored by the classes (<fc>), and the click command is
+// executed on left click (<action>).
+func Xmobar(item BarItem, colors Colors, click string) string {
+	text := oneLine(item.Text)
+	if strings.Contains(text, "<") {
+		// the raw tag prevents the interpretation of the summary as markup
+		text = fmt.Sprintf("<raw=%d:%s/>", utf8.RuneCountInString(text), text)
+	}
+	if color := colors.Color(item); color != "" {
+		text = "<fc=" + color + ">" + text + "</fc>"
+	}
+	if click != "" {
+		text = "<action=`" + click + "` button=1>" + text + "</action>"
+	}
+	return text
+}
+
+// Tmux formats the item for the status line of tmux, colored by the classes of the item.
+func Tmux(item BarItem, colors Colors) string {
+	style := ""
+	if color := colors.Color(item); color != "" {
+		style = "#[fg=" + color + "]"
+	}
+	if item.Urgent {
+		style += "#[bold]"
+	}
+	text := strings.ReplaceAll(oneLine(item.Text), "#", "##")
+	if style == "" {
+		return text
+	}
+	return style + text + "#[default]"
+}
+
+// Plain formats the item as plain text. The lines of the text (in two-line mode) are joined with the separator, and
+// the text is truncated to maxWidth characters (0 means no limit).
+func Plain(item BarItem, separator string, maxWidth int) string {
+	lines := strings.Split(strings.TrimSpace(item.Text), "\n")
+	return truncateRunes(strings.Join(lines, separator), maxWidth)
+}
+
+// Xbar formats the item as the output of an xbar (or SwiftBar) plugin: the text is the title, the events are the
+// lines of the dropdown menu. The events with video link open the meeting on click.
+func Xbar(item BarItem, events []Event, colors Colors, opts Options) string {
+	title := xbarText(oneLine(item.Text))
+	if color := colors.Color(item); color != "" {
+		title += " | color=" + color
+	}
+	lines := []string{title, "---"}
+	for _, event := range events {
+		line := xbarText(tooltipTime(event, opts) + " " + event.Raw.Summary)
+		if link := event.VideoLink(); link != "" {
+			line += " | href=" + link
+		}
+		lines = append(lines, line)
+	}
+	if len(events) == 0 && item.Tooltip != "" {
+		for _, line := range strings.Split(strings.TrimSpace(item.Tooltip), "\n") {
+			lines = append(lines, xbarText(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// xbarText replaces the pipe character, which separates the text and the parameters of the xbar lines.
+func xbarText(text string) string {
+	return strings.ReplaceAll(text, "|", "¦")
+}
+
+// EwwItem is the JSON object of the eww format, for the deflisten (or defpoll) variables of eww widgets.
+type EwwItem struct {
+	Text       string `json:"text"`
+	Tooltip    string `json:"tooltip"`
+	Class      string `json:"class"`
+	Percentage int    `json:"percentage"`
+	Urgent     bool   `json:"urgent"`
+	// Countdown is the time until the start of the next event ("in 12m"), empty if there is no next event.
+	Countdown string     `json:"countdown"`
+	Events    []EwwEvent `json:"events"`
+}
+
+// EwwEvent is one event of the day in the eww format.
+type EwwEvent struct {
+	Summary    string    `json:"summary"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	AllDay     bool      `json:"all_day"`
+	Time       string    `json:"time"`
+	Location   string    `json:"location"`
+	Link       string    `json:"link"`
+	Calendar   string    `json:"calendar"`
+	InProgress bool      `json:"in_progress"`
+	Finished   bool      `json:"finished"`
+	Countdown  string    `json:"countdown"`
+}
+
+// Eww formats the item and the events as one line JSON for eww.
+func Eww(item BarItem, events []Event, now time.Time, opts Options) (string, error) {
+	res := EwwItem{
+		Text:       item.Text,
+		Tooltip:    item.Tooltip,
+		Class:      strings.Join(item.Class, " "),
+		Percentage: item.Percentage,
+		Urgent:     item.Urgent,
+		Events:     []EwwEvent{},
+	}
+	if next := SelectNext(events, now, opts); next != nil {
+		res.Countdown = Countdown(next.Start.Sub(now))
+	}
+	for _, event := range events {
+		res.Events = append(res.Events, EwwEvent{
+			Summary:    event.Raw.Summary,
+			Start:      event.Start,
+			End:        event.End,
+			AllDay:     event.AllDay,
+			Time:       tooltipTime(event, opts),
+			Location:   event.Raw.Location,
+			Link:       event.VideoLink(),
+			Calendar:   event.Calendar,
+			InProgress: event.InProgress(now),
+			Finished:   event.Finished(now),
+			Countdown:  Countdown(event.Start.Sub(now)),
+		})
+	}
+	content, err := json.Marshal(res)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	return string(content), nil
+}
+
+// Yambar formats the item as the tags of the yambar script module (name|type|value lines). The empty line at the end
+// closes the transaction.
+func Yambar(item BarItem, events []Event, now time.Time, opts Options) string {
+	state := ""
+	for _, class := range item.Class {
+		switch class {
+		case "in-meeting", "imminent", "upcoming", "idle", "error":
+			if state == "" {
+				state = class
+			}
+		}
+	}
+	countdown := ""
+	if next := SelectNext(events, now, opts); next != nil {
+		countdown = Countdown(next.Start.Sub(now))
+	}
+	lines := []string{
+		"text|string|" + oneLine(item.Text),
+		"class|string|" + strings.Join(item.Class, " "),
+		"state|string|" + state,
+		"countdown|string|" + countdown,
+		fmt.Sprintf("percentage|range:0-100|%d", item.Percentage),
+		fmt.Sprintf("urgent|bool|%t", item.Urgent),
+		fmt.Sprintf("in_progress|bool|%t", item.InProgress),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
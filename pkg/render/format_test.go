@@ -0,0 +1,38 @@
+package render
+
+import (
+	"testing"
+)
+
+func TestPolybar(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     BarItem
+		colors   Colors
+		click    string
+		expected string
+	}{
+		{name: "plain", item: BarItem{Text: "10:00 Review"}, expected: "10:00 Review"},
+		{name: "percent", item: BarItem{Text: "10:00 100% review"}, expected: "10:00 100%% review"},
+		{name: "two-line", item: BarItem{Text: "10:00\nReview"}, expected: "10:00 Review"},
+		{
+			name:     "color and urgent",
+			item:     BarItem{Text: "10:00 Review", Class: []string{"imminent"}, Urgent: true},
+			colors:   Colors{"imminent": "#ff5555"},
+			expected: "%{+u}%{F#ff5555}10:00 Review%{F-}%{-u}",
+		},
+		{
+			name:     "click",
+			item:     BarItem{Text: "Review"},
+			click:    "waybar-google-calendar-check join --profile a:b",
+			expected: `%{A1:waybar-google-calendar-check join --profile a\:b:}Review%{A}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Polybar(tc.item, tc.colors, tc.click); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
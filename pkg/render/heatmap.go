@@ -0,0 +1,70 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var heatmapLevels = []rune("▁▂▃▄▅▆▇█")
+
+// hourlyBusy returns the first busy hour and the busy ratio (0-1) of each hour from there until the last busy hour.
+func hourlyBusy(events []Event) (time.Time, []float64) {
+	var first, last time.Time
+	for _, event := range events {
+		if event.AllDay || !event.End.After(event.Start) {
+			continue
+		}
+		if first.IsZero() || event.Start.Before(first) {
+			first = event.Start
+		}
+		if event.End.After(last) {
+			last = event.End
+		}
+	}
+	if first.IsZero() {
+		return first, nil
+	}
+	// Truncate would round in UTC, which is not the start of the local hour in zones like +05:30
+	first = time.Date(first.Year(), first.Month(), first.Day(), first.Hour(), 0, 0, 0, first.Location())
+
+	var buckets []float64
+	for hour := first; hour.Before(last); hour = hour.Add(time.Hour) {
+		busy := time.Duration(0)
+		for _, event := range events {
+			if event.AllDay {
+				continue
+			}
+			from, to := event.Start, event.End
+			if from.Before(hour) {
+				from = hour
+			}
+			if to.After(hour.Add(time.Hour)) {
+				to = hour.Add(time.Hour)
+			}
+			if to.After(from) {
+				busy += to.Sub(from)
+			}
+		}
+		ratio := busy.Hours()
+		if ratio > 1 {
+			ratio = 1
+		}
+		buckets = append(buckets, ratio)
+	}
+	return first, buckets
+}
+
+// heatmap renders the meeting density of the day as a compact sparkline ("09▃ 10█ 11▁").
+func heatmap(events []Event) string {
+	first, buckets := hourlyBusy(events)
+	var parts []string
+	for i, ratio := range buckets {
+		level := int(ratio * float64(len(heatmapLevels)))
+		if level >= len(heatmapLevels) {
+			level = len(heatmapLevels) - 1
+		}
+		parts = append(parts, fmt.Sprintf("%s%c", first.Add(time.Duration(i)*time.Hour).Format("15"), heatmapLevels[level]))
+	}
+	return strings.Join(parts, " ")
+}
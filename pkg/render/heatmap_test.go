@@ -0,0 +1,81 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"testing"
+	"time"
+)
+
+func TestHourlyBusy(t *testing.T) {
+	india := time.FixedZone("IST", 5*3600+30*60)
+	eucla := time.FixedZone("ACWST", 8*3600+45*60)
+	at := func(loc *time.Location, hour int, minute int) time.Time {
+		return time.Date(2021, 6, 1, hour, minute, 0, 0, loc)
+	}
+	event := func(start time.Time, end time.Time) Event {
+		return Event{Start: start, End: end, Raw: &calendar.Event{}}
+	}
+	allDay := NewEvent(&calendar.Event{
+		Start: &calendar.EventDateTime{Date: "2021-06-01"},
+		End:   &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	tests := []struct {
+		name     string
+		events   []Event
+		first    time.Time
+		buckets  []float64
+		sparkRow string
+	}{
+		{
+			name:     "sample day",
+			events:   []Event{event(at(time.UTC, 9, 0), at(time.UTC, 9, 15)), event(at(time.UTC, 10, 0), at(time.UTC, 11, 0)), event(at(time.UTC, 11, 30), at(time.UTC, 12, 0)), allDay},
+			first:    at(time.UTC, 9, 0),
+			buckets:  []float64{0.25, 1, 0.5},
+			sparkRow: "09▃ 10█ 11▅",
+		},
+		{
+			name:     "overlapping events",
+			events:   []Event{event(at(time.UTC, 9, 0), at(time.UTC, 10, 0)), event(at(time.UTC, 9, 30), at(time.UTC, 10, 0))},
+			first:    at(time.UTC, 9, 0),
+			buckets:  []float64{1},
+			sparkRow: "09█",
+		},
+		{
+			name:     "half hour offset",
+			events:   []Event{event(at(india, 9, 0), at(india, 9, 30)), event(at(india, 10, 15), at(india, 11, 0))},
+			first:    at(india, 9, 0),
+			buckets:  []float64{0.5, 0.75},
+			sparkRow: "09▅ 10▇",
+		},
+		{
+			name:     "three quarter offset",
+			events:   []Event{event(at(eucla, 13, 30), at(eucla, 14, 0))},
+			first:    at(eucla, 13, 0),
+			buckets:  []float64{0.5},
+			sparkRow: "13▅",
+		},
+		{
+			name:   "only all-day events",
+			events: []Event{allDay},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			first, buckets := hourlyBusy(tc.events)
+			if !first.Equal(tc.first) {
+				t.Fatalf("expected the first bucket at %s, got %s", tc.first, first)
+			}
+			if len(buckets) != len(tc.buckets) {
+				t.Fatalf("expected %v, got %v", tc.buckets, buckets)
+			}
+			for i := range buckets {
+				if buckets[i] != tc.buckets[i] {
+					t.Fatalf("expected %v, got %v", tc.buckets, buckets)
+				}
+			}
+			if row := heatmap(tc.events); row != tc.sparkRow {
+				t.Fatalf("expected %q, got %q", tc.sparkRow, row)
+			}
+		})
+	}
+}
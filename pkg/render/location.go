@@ -0,0 +1,100 @@
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// linkPattern matches the links (web, maps and geo: URIs) which are not the names of rooms.
+	linkPattern = regexp.MustCompile(`(?:https?://|geo:)\S+`)
+	// conferencePattern matches the meeting links of the well-known conference providers.
+	conferencePattern = regexp.MustCompile(`https://(?:[\w-]+\.)*(?:zoom\.us|zoomgov\.com|teams\.microsoft\.com|teams\.live\.com|webex\.com|meet\.google\.com|meet\.jit\.si|whereby\.com|chime\.aws)/[^\s<>"']+`)
+)
+
+// VideoLink returns the link of the video conference of the event (if any). The conference data is preferred, then
+// the known conference links of the location and the description. Other links (like maps) are not conferences.
+func (e Event) VideoLink() string {
+	if e.Raw.HangoutLink != "" {
+		return e.Raw.HangoutLink
+	}
+	if e.Raw.ConferenceData != nil {
+		for _, entry := range e.Raw.ConferenceData.EntryPoints {
+			if entry.EntryPointType == "video" {
+				return entry.Uri
+			}
+		}
+	}
+	for _, text := range []string{e.Raw.Location, e.Raw.Description} {
+		if link := conferencePattern.FindString(text); link != "" {
+			// the description is HTML
+			return html.UnescapeString(link)
+		}
+	}
+	return ""
+}
+
+// Room returns the name of the physical room of the event, or empty string for video meetings and locations which are
+// only links.
+func (e Event) Room() string {
+	if e.VideoLink() != "" {
+		return ""
+	}
+	room := strings.TrimSpace(strings.Split(e.Raw.Location, ",")[0])
+	if linkPattern.MatchString(room) {
+		return ""
+	}
+	return room
+}
+
+// ConferenceID returns the identifier of the conference (eg. the meeting code of Google Meet).
+func (e Event) ConferenceID() string {
+	if e.Raw.ConferenceData == nil {
+		return ""
+	}
+	return e.Raw.ConferenceData.ConferenceId
+}
+
+// DialIn returns the phone number and the PIN of the conference (if there is a phone entry point).
+func (e Event) DialIn() (number string, pin string) {
+	if e.Raw.ConferenceData == nil {
+		return "", ""
+	}
+	for _, entry := range e.Raw.ConferenceData.EntryPoints {
+		if entry.EntryPointType != "phone" {
+			continue
+		}
+		number = entry.Label
+		if number == "" {
+			number = strings.TrimPrefix(entry.Uri, "tel:")
+		}
+		pin = entry.Pin
+		if pin == "" {
+			pin = entry.AccessCode
+		}
+		return number, pin
+	}
+	return "", ""
+}
+
+var (
+	// coordinatesPattern matches raw coordinates, like "47.4979, 19.0402".
+	coordinatesPattern = regexp.MustCompile(`(-?\d{1,2}\.\d+)\s*,\s*(-?\d{1,3}\.\d+)`)
+	// mapsPattern matches the coordinates of Google Maps links (/@lat,lng, ?q=lat,lng, ll=lat,lng).
+	mapsPattern = regexp.MustCompile(`(?:@|[?&](?:q|ll|query|destination)=)(-?\d{1,2}\.\d+)(?:,|%2C)\s*(-?\d{1,3}\.\d+)`)
+)
+
+// GeoURI returns a geo: URI from the coordinates or the maps link of the location.
+func (e Event) GeoURI() string {
+	return parseGeo(e.Raw.Location)
+}
+
+func parseGeo(location string) string {
+	for _, pattern := range []*regexp.Regexp{mapsPattern, coordinatesPattern} {
+		if match := pattern.FindStringSubmatch(location); match != nil {
+			return "geo:" + match[1] + "," + match[2]
+		}
+	}
+	return ""
+}
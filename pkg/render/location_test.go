@@ -0,0 +1,214 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"testing"
+)
+
+func TestConferenceIDAndDialIn(t *testing.T) {
+	tests := []struct {
+		name       string
+		conference *calendar.ConferenceData
+		id         string
+		number     string
+		pin        string
+		tooltip    string
+	}{
+		{name: "no conference"},
+		{
+			name: "video only",
+			conference: &calendar.ConferenceData{
+				ConferenceId: "abc-defg-hij",
+				EntryPoints:  []*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/abc-defg-hij"}},
+			},
+			id:      "abc-defg-hij",
+			tooltip: "10:00 Review [abc-defg-hij]",
+		},
+		{
+			name: "phone with label and pin",
+			conference: &calendar.ConferenceData{
+				ConferenceId: "abc-defg-hij",
+				EntryPoints: []*calendar.EntryPoint{
+					{EntryPointType: "video", Uri: "https://meet.google.com/abc-defg-hij"},
+					{EntryPointType: "phone", Uri: "tel:+1-555-0100", Label: "+1 555-0100", Pin: "123456"},
+				},
+			},
+			id:      "abc-defg-hij",
+			number:  "+1 555-0100",
+			pin:     "123456",
+			tooltip: "10:00 Review [abc-defg-hij, ☎ +1 555-0100 PIN 123456]",
+		},
+		{
+			name: "phone without label, with access code",
+			conference: &calendar.ConferenceData{
+				EntryPoints: []*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+15550100", AccessCode: "987"}},
+			},
+			number:  "+15550100",
+			pin:     "987",
+			tooltip: "10:00 Review [☎ +15550100 PIN 987]",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event := testEvent("Review", clock(10, 0), clock(11, 0))
+			event.Raw.ConferenceData = tc.conference
+			if id := event.ConferenceID(); id != tc.id {
+				t.Fatalf("expected id %q, got %q", tc.id, id)
+			}
+			number, pin := event.DialIn()
+			if number != tc.number || pin != tc.pin {
+				t.Fatalf("expected %q/%q, got %q/%q", tc.number, tc.pin, number, pin)
+			}
+
+			opts := defaultOptions()
+			opts.MeetingNumber = true
+			line, err := tooltipLine(event, false, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := tc.tooltip
+			if expected == "" {
+				expected = "10:00 Review"
+			}
+			if line != expected {
+				t.Fatalf("expected tooltip %q, got %q", expected, line)
+			}
+		})
+	}
+}
+
+func TestVideoLinkAndRoom(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    calendar.Event
+		link     string
+		room     string
+		geo      string
+		headline string
+	}{
+		{
+			name:     "room",
+			event:    calendar.Event{Location: "Room 4.1, Building A"},
+			room:     "Room 4.1",
+			headline: "10:00 Review 🚪 Room 4.1",
+		},
+		{
+			name:     "hangout link",
+			event:    calendar.Event{HangoutLink: "https://meet.google.com/abc-defg-hij", Location: "Room 4.1"},
+			link:     "https://meet.google.com/abc-defg-hij",
+			headline: "10:00 Review 📹",
+		},
+		{
+			name: "conference entry point",
+			event: calendar.Event{ConferenceData: &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+				{EntryPointType: "phone", Uri: "tel:+15550100"},
+				{EntryPointType: "video", Uri: "https://teams.microsoft.com/l/meetup-join/1"},
+			}}},
+			link:     "https://teams.microsoft.com/l/meetup-join/1",
+			headline: "10:00 Review 📹",
+		},
+		{
+			name:     "zoom in the location",
+			event:    calendar.Event{Location: "Zoom: https://company.zoom.us/j/123456?pwd=abc"},
+			link:     "https://company.zoom.us/j/123456?pwd=abc",
+			headline: "10:00 Review 📹",
+		},
+		{
+			name:     "webex in the html description",
+			event:    calendar.Event{Description: `Join: <a href="https://company.webex.com/meet/john?a=1&amp;b=2">link</a>`},
+			link:     "https://company.webex.com/meet/john?a=1&b=2",
+			headline: "10:00 Review 📹",
+		},
+		{
+			name:     "conference link of the description wins over other links of the location",
+			event:    calendar.Event{Location: "https://example.com/room", Description: "https://meet.jit.si/standup"},
+			link:     "https://meet.jit.si/standup",
+			headline: "10:00 Review 📹",
+		},
+		{
+			name:     "other link of the location is not a conference",
+			event:    calendar.Event{Location: "see https://example.com/room"},
+			headline: "10:00 Review",
+		},
+		{
+			name:     "maps link of the location is not a conference",
+			event:    calendar.Event{Location: "https://www.google.com/maps/place/Cafe/@47.4979,19.0402,17z"},
+			geo:      "geo:47.4979,19.0402",
+			headline: "10:00 Review",
+		},
+		{
+			name:     "geo uri of the location is not a conference",
+			event:    calendar.Event{Location: "geo:47.4979,19.0402"},
+			geo:      "geo:47.4979,19.0402",
+			headline: "10:00 Review",
+		},
+		{
+			name:     "room with a link",
+			event:    calendar.Event{Location: "Cafe Central, https://example.com/cafe"},
+			room:     "Cafe Central",
+			headline: "10:00 Review 🚪 Cafe Central",
+		},
+		{
+			name:     "other links of the description are ignored",
+			event:    calendar.Event{Location: "Kitchen", Description: "agenda: https://docs.example.com/agenda"},
+			room:     "Kitchen",
+			headline: "10:00 Review 🚪 Kitchen",
+		},
+		{
+			name:     "nothing",
+			headline: "10:00 Review",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event := testEvent("Review", clock(10, 0), clock(11, 0))
+			tc.event.Summary = event.Raw.Summary
+			tc.event.Start = event.Raw.Start
+			tc.event.End = event.Raw.End
+			event.Raw = &tc.event
+			if link := event.VideoLink(); link != tc.link {
+				t.Fatalf("expected link %q, got %q", tc.link, link)
+			}
+			if room := event.Room(); room != tc.room {
+				t.Fatalf("expected room %q, got %q", tc.room, room)
+			}
+			if geo := event.GeoURI(); geo != tc.geo {
+				t.Fatalf("expected geo %q, got %q", tc.geo, geo)
+			}
+
+			opts := defaultOptions()
+			opts.ShowRoom = true
+			opts.RoomIcon = "🚪"
+			opts.VideoIcon = "📹"
+			text, err := headline(event, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tc.headline {
+				t.Fatalf("expected headline %q, got %q", tc.headline, text)
+			}
+		})
+	}
+}
+
+func TestParseGeo(t *testing.T) {
+	tests := []struct {
+		location string
+		expected string
+	}{
+		{location: "47.4979, 19.0402", expected: "geo:47.4979,19.0402"},
+		{location: "Office (-33.8688,151.2093)", expected: "geo:-33.8688,151.2093"},
+		{location: "https://www.google.com/maps/place/Cafe/@47.4979,19.0402,17z", expected: "geo:47.4979,19.0402"},
+		{location: "https://maps.google.com/?q=47.4979,19.0402", expected: "geo:47.4979,19.0402"},
+		{location: "https://www.google.com/maps/dir/?api=1&destination=47.4979%2C19.0402", expected: "geo:47.4979,19.0402"},
+		{location: "https://maps.apple.com/?ll=47.4979,19.0402", expected: "geo:47.4979,19.0402"},
+		{location: "Room 4.1, Building A"},
+		{location: "Main street 12, 1051 Budapest"},
+		{location: ""},
+	}
+	for _, tc := range tests {
+		if got := parseGeo(tc.location); got != tc.expected {
+			t.Errorf("parseGeo(%q): expected %q, got %q", tc.location, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package render
+
+import (
+	"time"
+)
+
+// StartOfDay returns the midnight before t, in the location of t.
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// leftover returns the timed event which is started before today, but still in progress.
+func leftover(events []Event, now time.Time) *Event {
+	midnight := StartOfDay(now)
+	for i := range events {
+		if !events[i].AllDay && events[i].Start.Before(midnight) && events[i].InProgress(now) {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// Skipped returns true if the event is hidden because of the response of the current user.
+// Declined events are kept if they should be shown in the tooltip.
+func Skipped(event Event, opts Options) bool {
+	status := event.ResponseStatus()
+	if status == "" || (opts.DeclinedInTooltipOnly && status == "declined") {
+		return false
+	}
+	return contains(opts.SkipResponseStatus, status)
+}
+
+// busy returns true if there is a running (timed) meeting.
+func busy(events []Event, now time.Time, opts Options) bool {
+	for _, event := range events {
+		if !event.AllDay && HeadlineCandidate(event, opts) && event.InProgress(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// allDayOnly returns true if there are only all-day events.
+func allDayOnly(events []Event) bool {
+	for _, event := range events {
+		if !event.AllDay {
+			return false
+		}
+	}
+	return len(events) > 0
+}
+
+// SelectNext returns the event which should be shown in the bar (or nil).
+func SelectNext(events []Event, now time.Time, opts Options) *Event {
+	// the running meeting is kept (after the grace period) if its end is shown
+	if opts.ShowCurrent || opts.UntilEnd || opts.Progress {
+		for i := range events {
+			if HeadlineCandidate(events[i], opts) && current(events[i], now, opts) {
+				return &events[i]
+			}
+		}
+	}
+	for i := range events {
+		if HeadlineCandidate(events[i], opts) && upcoming(events[i], now, opts) && !tooLongOngoing(events[i], now, opts) {
+			return &events[i]
+		}
+	}
+	if opts.ShowLeftover {
+		if event := leftover(events, now); event != nil && !tooLongOngoing(*event, now, opts) {
+			return event
+		}
+	}
+	return nil
+}
+
+// current returns true if the timed event is in progress, and it's not yet time to advance to the following one.
+func current(event Event, now time.Time, opts Options) bool {
+	if event.AllDay || !event.InProgress(now) || tooLongOngoing(event, now, opts) {
+		return false
+	}
+	return opts.AdvanceBefore <= 0 || now.Before(event.End.Add(-opts.AdvanceBefore))
+}
+
+// tooLongOngoing returns true if the event is in progress for longer than the configured maximum age.
+func tooLongOngoing(event Event, now time.Time, opts Options) bool {
+	return opts.MaxAgeOngoing > 0 && event.InProgress(now) && now.Sub(event.Start) > opts.MaxAgeOngoing
+}
+
+// HeadlineCandidate returns true if the event can be the next event (depending on the options, but not the time).
+func HeadlineCandidate(event Event, opts Options) bool {
+	switch {
+	case opts.DeclinedInTooltipOnly && event.Declined():
+		return false
+	case opts.AllDayBanner && event.AllDay:
+		return false
+	case opts.OutOfOffice && event.OutOfOffice():
+		return false
+	case opts.DropNoAttendeesHeadline && len(event.Raw.Attendees) == 0:
+		return false
+	case opts.ConfirmedOnly && event.Raw.Status != "confirmed":
+		return false
+	}
+	return true
+}
+
+// upcoming returns true if the event can be shown as the next event at the given time. Started events are kept for the
+// grace period, or until the maximum age of ongoing events if it's set.
+func upcoming(event Event, now time.Time, opts Options) bool {
+	if opts.AdvanceBefore > 0 && !event.End.IsZero() && !now.Before(event.End.Add(-opts.AdvanceBefore)) {
+		return false
+	}
+	keep := opts.GracePeriod
+	if opts.MaxAgeOngoing > 0 {
+		keep = opts.MaxAgeOngoing
+	}
+	return now.Before(event.Start.Add(keep))
+}
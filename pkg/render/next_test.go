@@ -0,0 +1,178 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"testing"
+	"time"
+)
+
+func TestSelectNext(t *testing.T) {
+	events := []Event{
+		testEvent("A", clock(10, 0), clock(11, 0)),
+		testEvent("B", clock(11, 0), clock(12, 0)),
+		testEvent("C", clock(14, 0), clock(18, 0)),
+	}
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		now      time.Time
+		expected string
+	}{
+		{name: "before the first", now: clock(9, 0), expected: "A"},
+		{name: "within the grace period", now: clock(10, 4), expected: "A"},
+		{name: "after the grace period", now: clock(10, 6), expected: "B"},
+		{name: "after the last", now: clock(18, 30), expected: ""},
+
+		{name: "max age keeps the ongoing event", opts: func(o *Options) { o.MaxAgeOngoing = time.Hour }, now: clock(14, 30), expected: "C"},
+		{name: "max age advances", opts: func(o *Options) { o.MaxAgeOngoing = time.Hour }, now: clock(15, 1), expected: ""},
+		{name: "max age shorter than the grace period", opts: func(o *Options) { o.MaxAgeOngoing = time.Minute }, now: clock(10, 2), expected: "B"},
+		{name: "max age with show current", opts: func(o *Options) { o.MaxAgeOngoing = time.Hour; o.ShowCurrent = true }, now: clock(15, 1), expected: ""},
+
+		{name: "advance before the end", opts: func(o *Options) { o.AdvanceBefore = 10 * time.Minute }, now: clock(10, 2), expected: "A"},
+		{name: "advance before the end, after the grace period", opts: func(o *Options) { o.AdvanceBefore = 10 * time.Minute }, now: clock(10, 55), expected: "B"},
+
+		{name: "show current", opts: func(o *Options) { o.ShowCurrent = true }, now: clock(10, 30), expected: "A"},
+		{name: "show current at the end", opts: func(o *Options) { o.ShowCurrent = true }, now: clock(10, 59), expected: "A"},
+		{name: "show current between the meetings", opts: func(o *Options) { o.ShowCurrent = true }, now: clock(12, 30), expected: "C"},
+		{name: "show current advances before the end", opts: func(o *Options) { o.ShowCurrent = true; o.AdvanceBefore = 10 * time.Minute }, now: clock(10, 55), expected: "B"},
+		{name: "show current before the advance", opts: func(o *Options) { o.ShowCurrent = true; o.AdvanceBefore = 10 * time.Minute }, now: clock(10, 45), expected: "A"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			got := ""
+			if next := SelectNext(events, tc.now, opts); next != nil {
+				got = next.Raw.Summary
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelectNextCandidates(t *testing.T) {
+	withAttendee := func(event Event, response string) Event {
+		event.Raw.Attendees = append(event.Raw.Attendees, &calendar.EventAttendee{Self: true, ResponseStatus: response})
+		return event
+	}
+	outOfOffice := testEvent("OOO", clock(9, 0), clock(10, 30))
+	outOfOffice.Raw.EventType = "outOfOffice"
+	tentative := withAttendee(testEvent("Tentative", clock(9, 30), clock(10, 0)), "tentative")
+	tentative.Raw.Status = "tentative"
+	allDay := NewEvent(&calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2021-06-01"},
+		End:     &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	yesterday := testEvent("Night shift", clock(-2, 0), clock(9, 30))
+
+	tests := []struct {
+		name     string
+		events   []Event
+		opts     func(*Options)
+		now      time.Time
+		expected string
+	}{
+		{
+			name:     "declined is shown by default",
+			events:   []Event{withAttendee(testEvent("Declined", clock(10, 0), clock(11, 0)), "declined")},
+			now:      clock(9, 0),
+			expected: "Declined",
+		},
+		{
+			name:     "declined only in the tooltip",
+			events:   []Event{withAttendee(testEvent("Declined", clock(10, 0), clock(11, 0)), "declined"), testEvent("B", clock(11, 0), clock(12, 0))},
+			opts:     func(o *Options) { o.DeclinedInTooltipOnly = true },
+			now:      clock(9, 0),
+			expected: "B",
+		},
+		{
+			name:     "out of office is skipped",
+			events:   []Event{outOfOffice, testEvent("B", clock(11, 0), clock(12, 0))},
+			opts:     func(o *Options) { o.OutOfOffice = true },
+			now:      clock(8, 0),
+			expected: "B",
+		},
+		{
+			name:     "confirmed only",
+			events:   []Event{tentative, testEvent("B", clock(11, 0), clock(12, 0))},
+			opts:     func(o *Options) { o.ConfirmedOnly = true },
+			now:      clock(9, 0),
+			expected: "B",
+		},
+		{
+			name:     "without attendees",
+			events:   []Event{testEvent("Focus", clock(10, 0), clock(11, 0)), withAttendee(testEvent("B", clock(11, 0), clock(12, 0)), "accepted")},
+			opts:     func(o *Options) { o.DropNoAttendeesHeadline = true },
+			now:      clock(9, 0),
+			expected: "B",
+		},
+		{
+			name:     "all-day banner is not the next event",
+			events:   []Event{allDay, testEvent("B", clock(11, 0), clock(12, 0))},
+			opts:     func(o *Options) { o.AllDayBanner = true },
+			now:      clock(9, 0),
+			expected: "B",
+		},
+		{
+			name:   "leftover is not shown by default",
+			events: []Event{yesterday},
+			now:    clock(9, 0),
+		},
+		{
+			name:     "leftover of yesterday",
+			events:   []Event{yesterday},
+			opts:     func(o *Options) { o.ShowLeftover = true },
+			now:      clock(9, 0),
+			expected: "Night shift",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			got := ""
+			if next := SelectNext(tc.events, tc.now, opts); next != nil {
+				got = next.Raw.Summary
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	event := func(response string) Event {
+		event := testEvent("A", clock(10, 0), clock(11, 0))
+		if response != "" {
+			event.Raw.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: response}}
+		}
+		return event
+	}
+	tests := []struct {
+		name     string
+		response string
+		opts     Options
+		expected bool
+	}{
+		{name: "not invited", opts: Options{SkipResponseStatus: []string{"declined"}}},
+		{name: "accepted", response: "accepted", opts: Options{SkipResponseStatus: []string{"declined"}}},
+		{name: "declined", response: "declined", opts: Options{SkipResponseStatus: []string{"declined"}}, expected: true},
+		{name: "declined in the tooltip", response: "declined", opts: Options{SkipResponseStatus: []string{"declined"}, DeclinedInTooltipOnly: true}},
+		{name: "needs action", response: "needsAction", opts: Options{SkipResponseStatus: []string{"declined", "needsAction"}}, expected: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Skipped(event(tc.response), tc.opts); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,496 @@
+// Package render creates the waybar item (text, tooltip and classes) from the events of the calendars.
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// BarItem is the JSON object expected by waybar custom modules.
+type BarItem struct {
+	Text    string   `json:"text"`
+	Tooltip string   `json:"tooltip,omitempty"`
+	Class   []string `json:"class,omitempty"`
+	// Percentage is the elapsed part of the current meeting or the closeness of the next one (0-100).
+	Percentage int `json:"percentage,omitempty"`
+	// Urgent is set when the next meeting is about to start.
+	Urgent bool `json:"urgent,omitempty"`
+
+	// InProgress is true if a meeting is running, even if the bar already shows the following one.
+	InProgress bool `json:"-"`
+}
+
+// Options are the display options of the bar item (the flags of the run and watch commands).
+type Options struct {
+	Pango                   bool
+	DeclinedInTooltipOnly   bool
+	EmptyIcon               string
+	TextTemplate            string
+	TooltipTemplate         string
+	FetchExtended           bool
+	ExtendedKeys            []string
+	DeclinedByOthers        bool
+	DeclinedByOthersRatio   float64
+	AllDayBanner            bool
+	AllDayBannerSeparator   string
+	Icon                    string
+	HeadlineSeparator       string
+	IconSeparator           string
+	Heatmap                 bool
+	OutOfOffice             bool
+	OutOfOfficeBanner       string
+	MaxTitleWords           int
+	ShowGuests              bool
+	PrefixDate              bool
+	DimPastOpacity          int
+	ShowRoom                bool
+	RoomIcon                string
+	VideoIcon               string
+	TwoLine                 bool
+	AdvanceBefore           time.Duration
+	SuppressAllDayOnly      bool
+	AllDayOnlyText          string
+	ShowLeftover            bool
+	RelativeAndAbsolute     bool
+	AbsoluteFirst           bool
+	MeetingNumber           bool
+	DropNoAttendeesTooltip  bool
+	DropNoAttendeesHeadline bool
+	MaxAgeOngoing           time.Duration
+	TimeRange               bool
+	OrganizerInitial        bool
+	CountdownUnder          time.Duration
+	UntilEnd                bool
+	ConfirmedOnly           bool
+	Progress                bool
+	ProgressWidth           int
+	MaxGuests               int
+	Imminent                time.Duration
+	SkipResponseStatus      []string
+	GracePeriod             time.Duration
+	ShowCurrent             bool
+	ShowFree                bool
+	Countdown               bool
+	TimeFormat              string
+	TwelveHour              bool
+	ShowVideoLink           bool
+}
+
+// Render creates the waybar item from the (sorted) events of the day.
+func Render(events []Event, now time.Time, opts Options) (BarItem, error) {
+	if len(events) == 0 {
+		return BarItem{
+			Text:  emptyText(opts),
+			Class: []string{"idle", "free"},
+		}, nil
+	}
+
+	next := SelectNext(events, now, opts)
+	running := busy(events, now, opts)
+	var banners []string
+	outOfOffice := false
+	alt := ""
+	for i := 0; i < len(events); i++ {
+		if opts.AllDayBanner && allDayNow(events[i], now) {
+			banners = append(banners, events[i].Raw.Summary)
+		}
+		if opts.OutOfOffice && events[i].OutOfOffice() && events[i].InProgress(now) {
+			outOfOffice = true
+		}
+		if opts.DropNoAttendeesTooltip && len(events[i].Raw.Attendees) == 0 {
+			continue
+		}
+		declined := opts.DeclinedInTooltipOnly && events[i].Declined()
+		line, err := tooltipLine(events[i], declined, now, opts)
+		if err != nil {
+			return BarItem{}, err
+		}
+		alt += line + "\n"
+	}
+	if opts.Heatmap {
+		if row := heatmap(events); row != "" {
+			alt += row + "\n"
+		}
+	}
+
+	var class []string
+	if len(banners) > 0 {
+		class = append(class, "all-day-banner")
+	}
+	if outOfOffice {
+		class = append(class, "ooo")
+		if opts.OutOfOfficeBanner != "" {
+			banners = append([]string{opts.OutOfOfficeBanner}, banners...)
+		}
+	}
+
+	if opts.SuppressAllDayOnly && allDayOnly(events) {
+		text := opts.AllDayOnlyText
+		if text == "" {
+			text = emptyText(opts)
+		}
+		return BarItem{
+			Text:       text,
+			Tooltip:    alt,
+			Class:      append(class, "idle", "free", "all-day-only"),
+			InProgress: running,
+		}, nil
+	}
+
+	if next == nil {
+		if len(banners) > 0 {
+			return BarItem{
+				Text:       strings.Join(banners, opts.AllDayBannerSeparator),
+				Tooltip:    alt,
+				Class:      append(class, "free"),
+				InProgress: running,
+			}, nil
+		}
+		return BarItem{
+			Text:       emptyText(opts),
+			Tooltip:    alt,
+			Class:      append(class, "idle", "free"),
+			InProgress: running,
+		}, nil
+	}
+	text, err := headline(*next, now, opts)
+	if err != nil {
+		return BarItem{}, err
+	}
+	if opts.ShowFree && next.Start.Sub(now) >= time.Minute && !busy(events, now, opts) {
+		text = "free " + ShortDuration(next.Start.Sub(now))
+		if opts.Icon != "" {
+			text = opts.Icon + opts.IconSeparator + text
+		}
+	}
+	item := BarItem{
+		Text:       text,
+		Tooltip:    alt,
+		Class:      append(class, state(*next, now, opts)),
+		Percentage: percentage(*next, now),
+		Urgent:     !next.InProgress(now) && next.Start.Sub(now) <= opts.Imminent,
+		InProgress: running,
+	}
+	if len(banners) > 0 {
+		item.Text = strings.Join(append(banners, text), opts.AllDayBannerSeparator)
+	}
+	if opts.DeclinedByOthers {
+		declined, all := next.DeclinedByOthers()
+		if declined > 0 && float64(declined)/float64(all) >= opts.DeclinedByOthersRatio {
+			item.Class = append(item.Class, "attendees-declined")
+			item.Tooltip += fmt.Sprintf("%d/%d attendees declined %s\n", declined, all, TooltipText(next.Raw.Summary, opts))
+		}
+	}
+	return item, nil
+}
+
+// state returns the state class of the next event: in-meeting, imminent or upcoming.
+func state(next Event, now time.Time, opts Options) string {
+	switch {
+	case next.InProgress(now):
+		return "in-meeting"
+	case next.Start.Sub(now) <= opts.Imminent:
+		return "imminent"
+	default:
+		return "upcoming"
+	}
+}
+
+// allDayNow returns true if the all-day event covers now (and not only a later day of the lookahead). Events without
+// end last one day.
+func allDayNow(event Event, now time.Time) bool {
+	if !event.AllDay {
+		return false
+	}
+	if event.End.IsZero() {
+		return StartOfDay(now).Equal(StartOfDay(event.Start))
+	}
+	return event.InProgress(now)
+}
+
+// percentage is the elapsed part of the running event, or how close the next event is within an hour.
+func percentage(next Event, now time.Time) int {
+	var value float64
+	if next.InProgress(now) {
+		value = next.Progress(now)
+	} else {
+		value = 1 - float64(next.Start.Sub(now))/float64(time.Hour)
+	}
+	return int(math.Round(100 * math.Max(0, math.Min(1, value))))
+}
+
+// ErrorItem is the bar item shown instead of the events when they couldn't be refreshed.
+func ErrorItem(err error) BarItem {
+	return BarItem{
+		Tooltip: err.Error(),
+		Class:   []string{"error"},
+	}
+}
+
+// emptyText is the bar text when there is no next event. It's a single line even in two-line mode, as there is no
+// time and summary to split.
+func emptyText(opts Options) string {
+	return opts.EmptyIcon
+}
+
+func headline(event Event, now time.Time, opts Options) (string, error) {
+	clock := FormatClock(event.Start, opts)
+	if day := relativeDay(event.Start, now); opts.PrefixDate && day != "" {
+		clock = day + " " + clock
+	}
+	if event.AllDay {
+		clock = "all day:"
+		if day := relativeDay(event.Start, now); opts.PrefixDate && day != "" {
+			clock = day + " " + clock
+		}
+	} else if opts.Progress && event.InProgress(now) {
+		clock = progressBar(event.Progress(now), opts.ProgressWidth)
+	} else if opts.UntilEnd && event.InProgress(now) {
+		clock = "ends " + Countdown(event.End.Sub(now))
+	} else if opts.CountdownUnder > 0 && event.Start.Sub(now) < opts.CountdownUnder {
+		clock = Countdown(event.Start.Sub(now))
+	} else if opts.RelativeAndAbsolute {
+		relative := Countdown(event.Start.Sub(now))
+		if opts.AbsoluteFirst {
+			clock = fmt.Sprintf("%s (%s)", clock, relative)
+		} else {
+			clock = fmt.Sprintf("%s (%s)", relative, clock)
+		}
+	}
+	separator := opts.HeadlineSeparator
+	if opts.TwoLine {
+		separator = "\n"
+	}
+	summary := truncateWords(event.Raw.Summary, opts.MaxTitleWords)
+	text := clock + separator + summary
+	if opts.ShowCurrent && !event.AllDay && event.InProgress(now) {
+		// the end of the running meeting, decorated with the progress bar and the remaining time if requested
+		end := "until " + FormatClock(event.End, opts)
+		if opts.UntilEnd {
+			end = "ends " + Countdown(event.End.Sub(now))
+		}
+		if opts.Progress {
+			end = progressBar(event.Progress(now), opts.ProgressWidth) + " " + end
+		}
+		text = summary + separator + end
+	} else if opts.Countdown && !event.AllDay {
+		text = summary + separator + Countdown(event.Start.Sub(now))
+	}
+	if opts.ShowRoom {
+		if room := event.Room(); room != "" {
+			text += " " + opts.RoomIcon + " " + room
+		} else if event.VideoLink() != "" {
+			text += " " + opts.VideoIcon
+		}
+	}
+	if opts.TextTemplate != "" {
+		var err error
+		text, err = executeTemplate(opts.TextTemplate, newTemplateData(event, now, opts))
+		if err != nil {
+			return "", err
+		}
+	}
+	if opts.OrganizerInitial {
+		if initial := event.OrganizerInitial(); initial != "" {
+			text = "[" + initial + "] " + text
+		}
+	}
+	if opts.Icon != "" {
+		text = opts.Icon + opts.IconSeparator + text
+	}
+	return text, nil
+}
+
+// progressBar renders the ratio (0-1) as a fixed width bar (▓▓▓░░).
+func progressBar(ratio float64, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	done := int(math.Round(ratio * float64(width)))
+	if done < 0 {
+		done = 0
+	}
+	if done > width {
+		done = width
+	}
+	return strings.Repeat("▓", done) + strings.Repeat("░", width-done)
+}
+
+// Countdown formats the time until the start of an event ("in 1h5m", "in 12m" or "now").
+func Countdown(d time.Duration) string {
+	if d < time.Minute {
+		return "now"
+	}
+	return "in " + ShortDuration(d)
+}
+
+// ShortDuration formats the duration with minute precision (eg. 1h45m).
+func ShortDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) - hours*60
+	if minutes == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%dm", hours, minutes)
+}
+
+// relativeDay returns a short name of the day of t, compared to now. Returns empty string for today.
+func relativeDay(t time.Time, now time.Time) string {
+	t = t.In(now.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+	days := int(math.Round(day.Sub(today).Hours() / 24))
+	switch {
+	case days == 0:
+		return ""
+	case days == 1:
+		return "tmrw"
+	case days > 1 && days < 7:
+		return t.Format("Mon")
+	default:
+		return t.Format("Jan 2")
+	}
+}
+
+// truncateWords keeps the first max words of the text (separated by single spaces), adding an ellipsis if anything is cut.
+func truncateWords(text string, max int) string {
+	if max <= 0 {
+		return text
+	}
+	words := strings.Fields(text)
+	if len(words) <= max {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:max], " ") + "…"
+}
+
+// FormatClock formats the time of day with the configured format.
+func FormatClock(t time.Time, opts Options) string {
+	return t.Format(ClockLayout(opts))
+}
+
+// ClockLayout returns the Go layout of --time-format, which can be a Go layout or a strftime format.
+func ClockLayout(opts Options) string {
+	if opts.TwelveHour {
+		return "3:04 PM"
+	}
+	if strings.Contains(opts.TimeFormat, "%") {
+		return strftimeLayout(opts.TimeFormat)
+	}
+	return opts.TimeFormat
+}
+
+var strftimeDirectives = map[byte]string{
+	'H': "15", 'I': "03", 'l': "3", 'M': "04", 'S': "05", 'p': "PM", 'P': "pm",
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'a': "Mon", 'A': "Monday", 'b': "Jan", 'B': "January",
+	'Z': "MST", 'z': "-0700", 'R': "15:04", 'T': "15:04:05", '%': "%",
+}
+
+// strftimeLayout converts the supported strftime directives to Go layout. Unknown directives are kept as is.
+func strftimeLayout(format string) string {
+	layout := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			if directive, found := strftimeDirectives[format[i+1]]; found {
+				layout.WriteString(directive)
+				i++
+				continue
+			}
+		}
+		layout.WriteByte(format[i])
+	}
+	return layout.String()
+}
+
+// tooltipTime returns the start time of the event, or the time range with --tooltip-time-range.
+func tooltipTime(event Event, opts Options) string {
+	if event.AllDay {
+		if opts.TimeRange {
+			return event.Start.Format("2006-01-02")
+		}
+		return "all day:"
+	}
+	if !opts.TimeRange {
+		return FormatClock(event.Start, opts)
+	}
+	if event.End.IsZero() {
+		return FormatClock(event.Start, opts)
+	}
+	return FormatClock(event.Start, opts) + "–" + FormatClock(event.End.In(event.Start.Location()), opts)
+}
+
+func tooltipLine(event Event, declined bool, now time.Time, opts Options) (string, error) {
+	var line string
+	if opts.TooltipTemplate != "" {
+		var err error
+		line, err = executeTemplate(opts.TooltipTemplate, newTemplateData(event, now, opts))
+		if err != nil {
+			return "", err
+		}
+	} else {
+		line = fmt.Sprintf("%s %s", tooltipTime(event, opts), TooltipText(event.Raw.Summary, opts))
+	}
+	if opts.MeetingNumber {
+		if details := meetingNumber(event); details != "" {
+			line += " [" + TooltipText(details, opts) + "]"
+		}
+	}
+	if opts.ShowGuests {
+		if guests := event.Guests(opts.MaxGuests); guests != "" {
+			line += " (" + TooltipText(guests, opts) + ")"
+		}
+	}
+	if opts.ShowVideoLink {
+		if link := event.VideoLink(); link != "" {
+			line += " " + TooltipText(link, opts)
+		}
+	}
+	if declined {
+		if opts.Pango {
+			line = "<s>" + line + "</s>"
+		} else {
+			line += " (declined)"
+		}
+	}
+	if opts.Pango && opts.DimPastOpacity > 0 && event.Finished(now) {
+		line = fmt.Sprintf("<span alpha=\"%d%%\">%s</span>", opts.DimPastOpacity, line)
+	}
+	return line, nil
+}
+
+// meetingNumber returns the conference id and the dial-in number (with the PIN) of the event ("abc-defg-hij, ☎ +1
+// 555-0100 PIN 123456").
+func meetingNumber(event Event) string {
+	var details []string
+	if id := event.ConferenceID(); id != "" {
+		details = append(details, id)
+	}
+	if number, pin := event.DialIn(); number != "" {
+		if pin != "" {
+			number += " PIN " + pin
+		}
+		details = append(details, "☎ "+number)
+	}
+	return strings.Join(details, ", ")
+}
+
+// TooltipText escapes the text if the tooltip uses pango markup.
+func TooltipText(s string, opts Options) string {
+	if opts.Pango {
+		return escapeMarkup(s)
+	}
+	return s
+}
+
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeMarkup escapes the characters which have special meaning in pango markup.
+func escapeMarkup(s string) string {
+	return markupEscaper.Replace(s)
+}
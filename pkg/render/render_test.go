@@ -0,0 +1,596 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDay is the day of the test events.
+var testDay = time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+
+// clock returns the given time of testDay.
+func clock(hour int, minute int) time.Time {
+	return testDay.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func testEvent(summary string, start time.Time, end time.Time) Event {
+	return NewEvent(&calendar.Event{
+		Summary: summary,
+		Status:  "confirmed",
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+	})
+}
+
+func defaultOptions() Options {
+	return Options{
+		HeadlineSeparator: " ",
+		GracePeriod:       5 * time.Minute,
+		ProgressWidth:     5,
+		TimeFormat:        "15:04",
+	}
+}
+
+func TestEmptyText(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{name: "no icon", expected: ""},
+		{name: "icon", opts: Options{EmptyIcon: "☕"}, expected: "☕"},
+		{name: "two-line", opts: Options{EmptyIcon: "☕", TwoLine: true}, expected: "☕"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := emptyText(tc.opts); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+			item, err := Render(nil, clock(9, 0), tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.expected {
+				t.Fatalf("expected %q in the bar, got %q", tc.expected, item.Text)
+			}
+		})
+	}
+}
+
+func TestHeadlineTwoLine(t *testing.T) {
+	opts := defaultOptions()
+	opts.TwoLine = true
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		now      time.Time
+		expected string
+	}{
+		{name: "upcoming", now: clock(9, 0), expected: "10:00\nReview"},
+		{name: "countdown", opts: func(o *Options) { o.Countdown = true }, now: clock(9, 0), expected: "Review\nin 1h"},
+		{name: "ongoing", opts: func(o *Options) { o.ShowCurrent = true }, now: clock(10, 30), expected: "Review\nuntil 11:00"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := opts
+			if tc.opts != nil {
+				tc.opts(&o)
+			}
+			got, err := headline(testEvent("Review", clock(10, 0), clock(11, 0)), tc.now, o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHeadlineOngoing(t *testing.T) {
+	events := []Event{
+		testEvent("Standup", clock(10, 0), clock(11, 0)),
+		testEvent("Review", clock(11, 0), clock(12, 0)),
+	}
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		now      time.Time
+		expected string
+	}{
+		{name: "until end, just after the start", opts: func(o *Options) { o.UntilEnd = true }, now: clock(10, 1), expected: "ends in 59m Standup"},
+		{name: "until end, near the end", opts: func(o *Options) { o.UntilEnd = true }, now: clock(10, 52), expected: "ends in 8m Standup"},
+		{name: "until end, last minute", opts: func(o *Options) { o.UntilEnd = true }, now: clock(10, 59).Add(30 * time.Second), expected: "ends now Standup"},
+		{name: "until end, between the meetings", opts: func(o *Options) { o.UntilEnd = true }, now: clock(12, 30), expected: ""},
+
+		{name: "progress at the start", opts: func(o *Options) { o.Progress = true }, now: clock(10, 0), expected: "░░░░░ Standup"},
+		{name: "progress at the half", opts: func(o *Options) { o.Progress = true }, now: clock(10, 30), expected: "▓▓▓░░ Standup"},
+		{name: "progress at the end", opts: func(o *Options) { o.Progress = true }, now: clock(10, 59).Add(59 * time.Second), expected: "▓▓▓▓▓ Standup"},
+		{name: "progress before the meeting", opts: func(o *Options) { o.Progress = true }, now: clock(9, 0), expected: "10:00 Standup"},
+
+		{name: "show current", opts: func(o *Options) { o.ShowCurrent = true }, now: clock(10, 30), expected: "Standup until 11:00"},
+		{name: "show current with progress", opts: func(o *Options) { o.ShowCurrent = true; o.Progress = true }, now: clock(10, 30), expected: "Standup ▓▓▓░░ until 11:00"},
+		{name: "show current with until end", opts: func(o *Options) { o.ShowCurrent = true; o.UntilEnd = true }, now: clock(10, 30), expected: "Standup ends in 30m"},
+		{name: "show current with both", opts: func(o *Options) { o.ShowCurrent = true; o.UntilEnd = true; o.Progress = true }, now: clock(10, 30), expected: "Standup ▓▓▓░░ ends in 30m"},
+		{name: "show current with relative", opts: func(o *Options) { o.ShowCurrent = true; o.RelativeAndAbsolute = true }, now: clock(10, 30), expected: "Standup until 11:00"},
+		{name: "show current, two-line", opts: func(o *Options) { o.ShowCurrent = true; o.Progress = true; o.TwoLine = true }, now: clock(10, 30), expected: "Standup\n▓▓▓░░ until 11:00"},
+		{name: "show current before the meeting", opts: func(o *Options) { o.ShowCurrent = true; o.Progress = true }, now: clock(9, 0), expected: "10:00 Standup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			item, err := Render(events, tc.now, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, item.Text)
+			}
+		})
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		ratio    float64
+		width    int
+		expected string
+	}{
+		{ratio: 0, width: 5, expected: "░░░░░"},
+		{ratio: 0.5, width: 4, expected: "▓▓░░"},
+		{ratio: 1, width: 5, expected: "▓▓▓▓▓"},
+		{ratio: 1.5, width: 3, expected: "▓▓▓"},
+		{ratio: -1, width: 3, expected: "░░░"},
+		{ratio: 0.5, width: 0, expected: "▓"},
+	}
+	for _, tc := range tests {
+		if got := progressBar(tc.ratio, tc.width); got != tc.expected {
+			t.Errorf("progressBar(%v, %d): expected %q, got %q", tc.ratio, tc.width, tc.expected, got)
+		}
+	}
+}
+
+func TestHeadline(t *testing.T) {
+	review := testEvent("Review", clock(10, 0), clock(11, 0))
+	organized := testEvent("Review", clock(10, 0), clock(11, 0))
+	organized.Raw.Organizer = &calendar.EventOrganizer{DisplayName: "dave", Email: "dave@example.com"}
+	tomorrow := testEvent("Review", clock(24+10, 0), clock(24+11, 0))
+	allDay := NewEvent(&calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2021-06-01"},
+		End:     &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+
+	tests := []struct {
+		name     string
+		event    Event
+		opts     func(*Options)
+		expected string
+	}{
+		{name: "default", event: review, expected: "10:00 Review"},
+		{name: "separator", event: review, opts: func(o *Options) { o.HeadlineSeparator = " · " }, expected: "10:00 · Review"},
+		{name: "icon", event: review, opts: func(o *Options) { o.Icon = "📅"; o.IconSeparator = "" }, expected: "📅10:00 Review"},
+		{name: "max title words", event: testEvent("Quarterly business review", clock(10, 0), clock(11, 0)), opts: func(o *Options) { o.MaxTitleWords = 2 }, expected: "10:00 Quarterly business…"},
+		{name: "tomorrow", event: tomorrow, opts: func(o *Options) { o.PrefixDate = true }, expected: "tmrw 10:00 Review"},
+		{name: "tomorrow without prefix", event: tomorrow, expected: "10:00 Review"},
+		{name: "relative and absolute", event: review, opts: func(o *Options) { o.RelativeAndAbsolute = true }, expected: "in 1h (10:00) Review"},
+		{name: "absolute first", event: review, opts: func(o *Options) { o.RelativeAndAbsolute = true; o.AbsoluteFirst = true }, expected: "10:00 (in 1h) Review"},
+		{name: "countdown under", event: review, opts: func(o *Options) { o.CountdownUnder = 2 * time.Hour }, expected: "in 1h Review"},
+		{name: "countdown over", event: review, opts: func(o *Options) { o.CountdownUnder = 30 * time.Minute }, expected: "10:00 Review"},
+		{name: "countdown", event: review, opts: func(o *Options) { o.Countdown = true }, expected: "Review in 1h"},
+		{name: "organizer initial", event: organized, opts: func(o *Options) { o.OrganizerInitial = true }, expected: "[D] 10:00 Review"},
+		{name: "all day", event: allDay, expected: "all day: Holiday"},
+		{name: "12-hour clock", event: review, opts: func(o *Options) { o.TwelveHour = true }, expected: "10:00 AM Review"},
+		{name: "strftime", event: review, opts: func(o *Options) { o.TimeFormat = "%H.%M" }, expected: "10.00 Review"},
+		{name: "template", event: review, opts: func(o *Options) { o.TextTemplate = "{{.Summary}} {{.Countdown}}" }, expected: "Review in 1h"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			got, err := headline(tc.event, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeclinedInTooltipOnly(t *testing.T) {
+	declined := testEvent("Sync", clock(10, 0), clock(11, 0))
+	declined.Raw.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "declined"}}
+	events := []Event{declined, testEvent("Review", clock(11, 0), clock(12, 0))}
+	tests := []struct {
+		name    string
+		pango   bool
+		tooltip string
+	}{
+		{name: "plain", tooltip: "10:00 Sync (declined)\n11:00 Review\n"},
+		{name: "pango", pango: true, tooltip: "<s>10:00 Sync</s>\n11:00 Review\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.DeclinedInTooltipOnly = true
+			opts.Pango = tc.pango
+			item, err := Render(events, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != "11:00 Review" {
+				t.Fatalf("declined event shouldn't be the next one, got %q", item.Text)
+			}
+			if item.Tooltip != tc.tooltip {
+				t.Fatalf("expected tooltip %q, got %q", tc.tooltip, item.Tooltip)
+			}
+		})
+	}
+}
+
+func TestIdle(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		class  []string
+	}{
+		{name: "empty day", class: []string{"idle", "free"}},
+		{name: "after the last event", events: []Event{testEvent("Standup", clock(8, 0), clock(8, 15))}, class: []string{"idle", "free"}},
+		{name: "upcoming event", events: []Event{testEvent("Review", clock(10, 0), clock(11, 0))}, class: []string{"upcoming"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.EmptyIcon = "☕"
+			item, err := Render(tc.events, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(item.Class, " ") != strings.Join(tc.class, " ") {
+				t.Fatalf("expected classes %v, got %v", tc.class, item.Class)
+			}
+			if idle := contains(item.Class, "idle"); idle != (item.Text == "☕") {
+				t.Fatalf("the idle glyph should be shown only with the idle class, got %q with %v", item.Text, item.Class)
+			}
+		})
+	}
+}
+
+func TestDeclinedByOthers(t *testing.T) {
+	withResponses := func(responses ...string) Event {
+		event := testEvent("Review", clock(10, 0), clock(11, 0))
+		event.Raw.Attendees = []*calendar.EventAttendee{
+			{Self: true, ResponseStatus: "accepted"},
+			{Resource: true, ResponseStatus: "declined"},
+		}
+		for _, response := range responses {
+			event.Raw.Attendees = append(event.Raw.Attendees, &calendar.EventAttendee{ResponseStatus: response})
+		}
+		return event
+	}
+	tests := []struct {
+		name     string
+		event    Event
+		ratio    float64
+		expected string
+	}{
+		{name: "nobody declined", event: withResponses("accepted", "accepted"), ratio: 0.5},
+		{name: "below the threshold", event: withResponses("declined", "accepted", "accepted"), ratio: 0.5},
+		{name: "at the threshold", event: withResponses("declined", "accepted"), ratio: 0.5, expected: "1/2 attendees declined Review\n"},
+		{name: "above the threshold", event: withResponses("declined", "declined", "accepted"), ratio: 0.5, expected: "2/3 attendees declined Review\n"},
+		{name: "any", event: withResponses("declined", "accepted", "accepted"), ratio: 0, expected: "1/3 attendees declined Review\n"},
+		{name: "no other attendees", event: withResponses(), ratio: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.DeclinedByOthers = true
+			opts.DeclinedByOthersRatio = tc.ratio
+			item, err := Render([]Event{tc.event}, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if marked := contains(item.Class, "attendees-declined"); marked != (tc.expected != "") {
+				t.Fatalf("unexpected classes %v", item.Class)
+			}
+			if note := strings.TrimPrefix(item.Tooltip, "10:00 Review\n"); note != tc.expected {
+				t.Fatalf("expected tooltip note %q, got %q", tc.expected, note)
+			}
+		})
+	}
+}
+
+func TestAllDayBanner(t *testing.T) {
+	allDay := func(summary string, from string, to string) Event {
+		return NewEvent(&calendar.Event{
+			Summary: summary,
+			Status:  "confirmed",
+			Start:   &calendar.EventDateTime{Date: from},
+			End:     &calendar.EventDateTime{Date: to},
+		})
+	}
+	holiday := allDay("🏖 Holiday", "2021-06-01", "2021-06-02")
+	conference := allDay("Conference", "2021-05-31", "2021-06-03")
+	tomorrow := allDay("Moving", "2021-06-02", "2021-06-03")
+	standup := testEvent("Standup", clock(10, 0), clock(10, 15))
+	tests := []struct {
+		name   string
+		events []Event
+		text   string
+		banner bool
+	}{
+		{name: "one banner", events: []Event{holiday, standup}, text: "🏖 Holiday | 10:00 Standup", banner: true},
+		{name: "multiple banners", events: []Event{conference, holiday, standup}, text: "Conference | 🏖 Holiday | 10:00 Standup", banner: true},
+		{name: "only banner", events: []Event{holiday}, text: "🏖 Holiday", banner: true},
+		{name: "all-day event of tomorrow", events: []Event{standup, tomorrow}, text: "10:00 Standup"},
+		{name: "no all-day event", events: []Event{standup}, text: "10:00 Standup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.AllDayBanner = true
+			opts.AllDayBannerSeparator = " | "
+			item, err := Render(tc.events, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.text {
+				t.Fatalf("expected %q, got %q", tc.text, item.Text)
+			}
+			if contains(item.Class, "all-day-banner") != tc.banner {
+				t.Fatalf("unexpected classes %v", item.Class)
+			}
+		})
+	}
+}
+
+func TestOutOfOffice(t *testing.T) {
+	outOfOffice := testEvent("Out of office", clock(8, 0), clock(12, 0))
+	outOfOffice.Raw.EventType = "outOfOffice"
+	review := testEvent("Review", clock(14, 0), clock(15, 0))
+	tests := []struct {
+		name   string
+		opts   func(*Options)
+		now    time.Time
+		text   string
+		ooo    bool
+		banner string
+	}{
+		{name: "during out of office", now: clock(9, 0), text: "14:00 Review", ooo: true},
+		{name: "with banner", opts: func(o *Options) { o.OutOfOfficeBanner = "🌴 OOO" }, now: clock(9, 0), text: "🌴 OOO | 14:00 Review", ooo: true},
+		{name: "after out of office", opts: func(o *Options) { o.OutOfOfficeBanner = "🌴 OOO" }, now: clock(13, 0), text: "14:00 Review"},
+		{name: "not respected", opts: func(o *Options) { o.OutOfOffice = false }, now: clock(7, 0), text: "08:00 Out of office"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.OutOfOffice = true
+			opts.AllDayBannerSeparator = " | "
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			item, err := Render([]Event{outOfOffice, review}, tc.now, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.text {
+				t.Fatalf("expected %q, got %q", tc.text, item.Text)
+			}
+			if contains(item.Class, "ooo") != tc.ooo {
+				t.Fatalf("unexpected classes %v", item.Class)
+			}
+		})
+	}
+}
+
+func TestGuests(t *testing.T) {
+	withGuests := func(names ...string) Event {
+		event := testEvent("Review", clock(10, 0), clock(11, 0))
+		event.Raw.Attendees = []*calendar.EventAttendee{
+			{Self: true, DisplayName: "me"},
+			{Resource: true, DisplayName: "Room 4.1"},
+		}
+		for _, name := range names {
+			event.Raw.Attendees = append(event.Raw.Attendees, &calendar.EventAttendee{DisplayName: name})
+		}
+		return event
+	}
+	tests := []struct {
+		name     string
+		event    Event
+		max      int
+		expected string
+	}{
+		{name: "small meeting", event: withGuests("Ann", "Bob"), max: 3, expected: "10:00 Review (Ann, Bob)"},
+		{name: "at the cap", event: withGuests("Ann", "Bob", "Cid"), max: 3, expected: "10:00 Review (Ann, Bob, Cid)"},
+		{name: "large meeting", event: withGuests("Ann", "Bob", "Cid", "Dan", "Eve"), max: 3, expected: "10:00 Review (Ann, Bob, Cid +2 more)"},
+		{name: "no cap", event: withGuests("Ann", "Bob", "Cid", "Dan"), expected: "10:00 Review (Ann, Bob, Cid, Dan)"},
+		{name: "email fallback", event: func() Event {
+			event := withGuests()
+			event.Raw.Attendees = append(event.Raw.Attendees, &calendar.EventAttendee{Email: "ann@example.com"})
+			return event
+		}(), max: 3, expected: "10:00 Review (ann@example.com)"},
+		{name: "no guests", event: withGuests(), max: 3, expected: "10:00 Review"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.ShowGuests = true
+			opts.MaxGuests = tc.max
+			line, err := tooltipLine(tc.event, false, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestDimPastEvents(t *testing.T) {
+	events := []Event{
+		testEvent("Standup", clock(9, 0), clock(9, 15)),
+		testEvent("Review", clock(10, 0), clock(11, 0)),
+		testEvent("Planning", clock(14, 0), clock(15, 0)),
+	}
+	tests := []struct {
+		name     string
+		pango    bool
+		opacity  int
+		expected string
+	}{
+		{
+			name:     "dimmed",
+			pango:    true,
+			opacity:  50,
+			expected: "<span alpha=\"50%\">09:00 Standup</span>\n10:00 Review\n14:00 Planning\n",
+		},
+		{name: "no dimming", pango: true, expected: "09:00 Standup\n10:00 Review\n14:00 Planning\n"},
+		{name: "without pango", opacity: 50, expected: "09:00 Standup\n10:00 Review\n14:00 Planning\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.Pango = tc.pango
+			opts.DimPastOpacity = tc.opacity
+			// Review is in progress, only Standup is finished
+			item, err := Render(events, clock(10, 30), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Tooltip != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, item.Tooltip)
+			}
+		})
+	}
+}
+
+func TestSuppressAllDayOnly(t *testing.T) {
+	holiday := NewEvent(&calendar.Event{
+		Summary: "Holiday",
+		Status:  "confirmed",
+		Start:   &calendar.EventDateTime{Date: "2021-06-01"},
+		End:     &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	standup := testEvent("Standup", clock(10, 0), clock(10, 15))
+	tests := []struct {
+		name   string
+		events []Event
+		text   string
+		class  string
+	}{
+		{name: "all-day only", events: []Event{holiday}, text: "🌴", class: "all-day-banner idle free all-day-only"},
+		{name: "mixed day", events: []Event{holiday, standup}, text: "Holiday | 10:00 Standup", class: "all-day-banner upcoming"},
+		{name: "empty day", text: "☕", class: "idle free"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.SuppressAllDayOnly = true
+			opts.AllDayOnlyText = "🌴"
+			opts.EmptyIcon = "☕"
+			opts.AllDayBanner = true
+			opts.AllDayBannerSeparator = " | "
+			item, err := Render(tc.events, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.text {
+				t.Fatalf("expected %q, got %q", tc.text, item.Text)
+			}
+			if class := strings.Join(item.Class, " "); class != tc.class {
+				t.Fatalf("expected classes %q, got %q", tc.class, class)
+			}
+		})
+	}
+}
+
+func TestDropNoAttendees(t *testing.T) {
+	withAttendees := func(event Event) Event {
+		event.Raw.Attendees = []*calendar.EventAttendee{{Self: true, ResponseStatus: "accepted"}, {DisplayName: "Ann"}}
+		return event
+	}
+	events := []Event{
+		testEvent("Focus", clock(9, 30), clock(10, 0)),
+		withAttendees(testEvent("Review", clock(10, 0), clock(11, 0))),
+		testEvent("Lunch", clock(12, 0), clock(13, 0)),
+	}
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		text    string
+		tooltip string
+	}{
+		{name: "kept by default", text: "09:30 Focus", tooltip: "09:30 Focus\n10:00 Review\n12:00 Lunch\n"},
+		{name: "dropped from the tooltip", opts: func(o *Options) { o.DropNoAttendeesTooltip = true }, text: "09:30 Focus", tooltip: "10:00 Review\n"},
+		{name: "dropped from both", opts: func(o *Options) { o.DropNoAttendeesTooltip = true; o.DropNoAttendeesHeadline = true }, text: "10:00 Review", tooltip: "10:00 Review\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			item, err := Render(events, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Text != tc.text {
+				t.Fatalf("expected %q, got %q", tc.text, item.Text)
+			}
+			if item.Tooltip != tc.tooltip {
+				t.Fatalf("expected tooltip %q, got %q", tc.tooltip, item.Tooltip)
+			}
+		})
+	}
+}
+
+func TestTooltipTimeRange(t *testing.T) {
+	allDay := NewEvent(&calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2021-06-01"},
+		End:     &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	noEnd := NewEvent(&calendar.Event{
+		Summary: "Reminder",
+		Start:   &calendar.EventDateTime{DateTime: clock(10, 0).Format(time.RFC3339)},
+	})
+	tests := []struct {
+		name     string
+		event    Event
+		opts     func(*Options)
+		expected string
+	}{
+		{name: "timed", event: testEvent("Standup", clock(9, 0), clock(9, 30)), expected: "09:00–09:30 Standup"},
+		{name: "12-hour clock", event: testEvent("Standup", clock(9, 0), clock(13, 30)), opts: func(o *Options) { o.TwelveHour = true }, expected: "9:00 AM–1:30 PM Standup"},
+		{name: "all day", event: allDay, expected: "2021-06-01 Holiday"},
+		{name: "missing end", event: noEnd, expected: "10:00 Reminder"},
+		{name: "without range", event: testEvent("Standup", clock(9, 0), clock(9, 30)), opts: func(o *Options) { o.TimeRange = false }, expected: "09:00 Standup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := defaultOptions()
+			opts.TimeRange = true
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			line, err := tooltipLine(tc.event, false, clock(8, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, line)
+			}
+		})
+	}
+}
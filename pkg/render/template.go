@@ -0,0 +1,80 @@
+package render
+
+import (
+	"github.com/zeebo/errs/v2"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the context of the --text-template and --tooltip-template templates.
+type templateData struct {
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	Location     string
+	Attendees    []string
+	Countdown    string
+	VideoLink    string
+	Extended     map[string]string
+	ConferenceID string
+	DialIn       string
+	PIN          string
+}
+
+func newTemplateData(event Event, now time.Time, opts Options) templateData {
+	dialIn, pin := event.DialIn()
+	return templateData{
+		Summary:      event.Raw.Summary,
+		Start:        event.Start,
+		End:          event.End,
+		Location:     event.Raw.Location,
+		Attendees:    event.AttendeeNames(),
+		Countdown:    Countdown(event.Start.Sub(now)),
+		VideoLink:    event.VideoLink(),
+		Extended:     extendedProperties(event, opts),
+		ConferenceID: event.ConferenceID(),
+		DialIn:       dialIn,
+		PIN:          pin,
+	}
+}
+
+// extendedProperties collects the shared and private extended properties of the event.
+// Private values win when the same key is defined in both.
+func extendedProperties(event Event, opts Options) map[string]string {
+	res := map[string]string{}
+	props := event.Raw.ExtendedProperties
+	if props == nil || (!opts.FetchExtended && len(opts.ExtendedKeys) == 0) {
+		return res
+	}
+	for _, values := range []map[string]string{props.Shared, props.Private} {
+		for k, v := range values {
+			if opts.FetchExtended || contains(opts.ExtendedKeys, k) {
+				res[k] = v
+			}
+		}
+	}
+	return res
+}
+
+func executeTemplate(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return "", errs.Errorf("invalid template %q: %v", text, err)
+	}
+	out := strings.Builder{}
+	err = tmpl.Execute(&out, data)
+	if err != nil {
+		return "", errs.Wrap(err)
+	}
+	return out.String(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
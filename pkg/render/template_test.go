@@ -0,0 +1,54 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"testing"
+)
+
+func TestExtendedProperties(t *testing.T) {
+	props := &calendar.EventExtendedProperties{
+		Private: map[string]string{"team": "platform", "ticket": "OPS-1"},
+		Shared:  map[string]string{"team": "infra", "room": "4.1"},
+	}
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		props    *calendar.EventExtendedProperties
+		template string
+		expected string
+	}{
+		{name: "private key", opts: func(o *Options) { o.ExtendedKeys = []string{"ticket"} }, props: props, template: "{{.Extended.ticket}} {{.Summary}}", expected: "OPS-1 Review"},
+		{name: "private wins over shared", opts: func(o *Options) { o.ExtendedKeys = []string{"team"} }, props: props, template: "{{.Extended.team}}", expected: "platform"},
+		{name: "shared key", opts: func(o *Options) { o.ExtendedKeys = []string{"room"} }, props: props, template: "{{.Extended.room}}", expected: "4.1"},
+		{name: "not selected key", opts: func(o *Options) { o.ExtendedKeys = []string{"room"} }, props: props, template: "{{.Extended.ticket}}", expected: "<no value>"},
+		{name: "all keys", opts: func(o *Options) { o.FetchExtended = true }, props: props, template: "{{.Extended.ticket}} {{.Extended.room}}", expected: "OPS-1 4.1"},
+		{name: "not enabled", props: props, template: "{{.Extended.ticket}}", expected: "<no value>"},
+		{name: "no properties", opts: func(o *Options) { o.FetchExtended = true }, template: "{{.Extended.ticket}}", expected: "<no value>"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event := testEvent("Review", clock(10, 0), clock(11, 0))
+			event.Raw.ExtendedProperties = tc.props
+			opts := defaultOptions()
+			if tc.opts != nil {
+				tc.opts(&opts)
+			}
+			opts.TextTemplate = tc.template
+			opts.TooltipTemplate = tc.template
+			text, err := headline(event, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, text)
+			}
+			line, err := tooltipLine(event, false, clock(9, 0), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != tc.expected {
+				t.Fatalf("expected tooltip %q, got %q", tc.expected, line)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"fmt"
+	"github.com/zeebo/errs/v2"
+	"strings"
+	"time"
+)
+
+// ParseWeekday returns the weekday of the (english, case insensitive, optionally abbreviated) name.
+func ParseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return day, nil
+		}
+	}
+	return time.Sunday, errs.Errorf("invalid weekday %q", name)
+}
+
+// StartOfWeek returns the midnight of the first day of the week which contains t.
+func StartOfWeek(t time.Time, first time.Weekday) time.Time {
+	day := StartOfDay(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(first) + 7) % 7))
+}
+
+// Week renders the events of the week starting at from, grouped by weekday. Weekends are omitted if ignoreWeekends is
+// set.
+func Week(events []Event, from time.Time, now time.Time, opts Options, ignoreWeekends bool) (string, error) {
+	out := ""
+	for i := 0; i < 7; i++ {
+		day := from.AddDate(0, 0, i)
+		if ignoreWeekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+			continue
+		}
+		next := day.AddDate(0, 0, 1)
+		header := day.Format("Mon Jan 2")
+		if StartOfDay(now).Equal(day) {
+			header += " (today)"
+		}
+		if opts.Pango {
+			header = "<b>" + header + "</b>"
+		}
+		out += header + "\n"
+		found := false
+		for _, event := range events {
+			if !event.Start.Before(next) || (!event.End.IsZero() && !event.End.After(day)) || (event.End.IsZero() && event.Start.Before(day)) {
+				continue
+			}
+			line, err := tooltipLine(event, false, now, opts)
+			if err != nil {
+				return "", err
+			}
+			out += fmt.Sprintf("  %s\n", line)
+			found = true
+		}
+		if !found {
+			out += "  -\n"
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"google.golang.org/api/calendar/v3"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var weekHeader = regexp.MustCompile(`(?m)^(Mon|Tue|Wed|Thu|Fri|Sat|Sun) `)
+
+func TestWeek(t *testing.T) {
+	event := func(summary string, start time.Time, length time.Duration) Event {
+		return NewEvent(&calendar.Event{
+			Summary: summary,
+			Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:     &calendar.EventDateTime{DateTime: start.Add(length).Format(time.RFC3339)},
+		})
+	}
+	// a Tuesday
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+	events := []Event{
+		event("Standup", now.Add(-2*time.Hour), 15*time.Minute),
+		event("Hike", now.AddDate(0, 0, 4).Add(-3*time.Hour), 6*time.Hour),
+	}
+	tests := []struct {
+		name           string
+		first          time.Weekday
+		ignoreWeekends bool
+		days           string
+	}{
+		{name: "monday first", first: time.Monday, days: "Mon Tue Wed Thu Fri Sat Sun"},
+		{name: "sunday first", first: time.Sunday, days: "Sun Mon Tue Wed Thu Fri Sat"},
+		{name: "monday first without weekends", first: time.Monday, ignoreWeekends: true, days: "Mon Tue Wed Thu Fri"},
+		{name: "sunday first without weekends", first: time.Sunday, ignoreWeekends: true, days: "Mon Tue Wed Thu Fri"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			from := StartOfWeek(now, tc.first)
+			if from.Weekday() != tc.first || from.After(now) || now.Sub(from) >= 7*24*time.Hour {
+				t.Fatalf("invalid start of the week %s", from)
+			}
+			out, err := Week(events, from, now, Options{}, tc.ignoreWeekends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var days []string
+			for _, match := range weekHeader.FindAllStringSubmatch(out, -1) {
+				days = append(days, match[1])
+			}
+			if got := strings.Join(days, " "); got != tc.days {
+				t.Fatalf("expected %s, got %s", tc.days, got)
+			}
+			if !strings.Contains(out, "Standup") {
+				t.Fatalf("missing event of the day:\n%s", out)
+			}
+			if strings.Contains(out, "Hike") == tc.ignoreWeekends {
+				t.Fatalf("weekend event should be shown only with weekends:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected time.Weekday
+		invalid  bool
+	}{
+		{name: "monday", expected: time.Monday},
+		{name: "Sunday", expected: time.Sunday},
+		{name: "sat", expected: time.Saturday},
+		{name: "su", invalid: true},
+		{name: "funday", invalid: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseWeekday(tc.name)
+			if (err != nil) != tc.invalid {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !tc.invalid && got != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"strings"
+	"time"
+)
+
+// prometheusMetrics renders the state of the day in the Prometheus text exposition format. Only the events which
+// could be shown in the headline are counted, and the meetings of today (not the lookahead).
+func prometheusMetrics(events []render.Event, now time.Time, opts render.Options) string {
+	count, _ := meetingLoad(dayMeetings(events, now, opts))
+	inMeeting := 0
+	var next *render.Event
+	for i, event := range events {
+		if event.AllDay || event.Declined() || !render.HeadlineCandidate(event, opts) {
+			continue
+		}
+		if event.InProgress(now) {
+			inMeeting = 1
+		}
+		if next == nil && event.Start.After(now) {
+			next = &events[i]
+		}
+	}
+
+	out := strings.Builder{}
+	out.WriteString("# HELP meetings_today Number of the timed events of the day.\n")
+	out.WriteString("# TYPE meetings_today gauge\n")
+	out.WriteString(fmt.Sprintf("meetings_today %d\n", count))
+	if next != nil {
+		out.WriteString("# HELP next_event_seconds Seconds until the start of the next event.\n")
+		out.WriteString("# TYPE next_event_seconds gauge\n")
+		out.WriteString(fmt.Sprintf("next_event_seconds %d\n", int(next.Start.Sub(now).Seconds())))
+	}
+	out.WriteString("# HELP in_meeting 1 if an event is in progress.\n")
+	out.WriteString("# TYPE in_meeting gauge\n")
+	out.WriteString(fmt.Sprintf("in_meeting %d\n", inMeeting))
+	return out.String()
+}
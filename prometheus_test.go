@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"google.golang.org/api/calendar/v3"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	at := func(hour int, minute int) string {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Format(time.RFC3339)
+	}
+	allDay := render.NewEvent(&calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2021-06-01"},
+		End:     &calendar.EventDateTime{Date: "2021-06-02"},
+	})
+	events := []render.Event{
+		allDay,
+		testEvent("Standup", at(9, 0), at(9, 15)),
+		declined(testEvent("Sync", at(10, 0), at(11, 0))),
+		testEvent("Review", at(11, 0), at(12, 0)),
+		testEvent("Planning", at(14, 0), at(15, 0)),
+		// lookahead of the next morning
+		testEvent("Breakfast", at(24+8, 0), at(24+9, 0)),
+	}
+	tests := []struct {
+		name     string
+		now      time.Time
+		opts     render.Options
+		expected string
+	}{
+		{
+			name: "before a meeting",
+			now:  day.Add(10*time.Hour + 30*time.Minute),
+			expected: "# HELP meetings_today Number of the timed events of the day.\n" +
+				"# TYPE meetings_today gauge\n" +
+				"meetings_today 3\n" +
+				"# HELP next_event_seconds Seconds until the start of the next event.\n" +
+				"# TYPE next_event_seconds gauge\n" +
+				"next_event_seconds 1800\n" +
+				"# HELP in_meeting 1 if an event is in progress.\n" +
+				"# TYPE in_meeting gauge\n" +
+				"in_meeting 0\n",
+		},
+		{
+			name: "in a meeting",
+			now:  day.Add(14*time.Hour + 30*time.Minute),
+			expected: "# HELP meetings_today Number of the timed events of the day.\n" +
+				"# TYPE meetings_today gauge\n" +
+				"meetings_today 3\n" +
+				"# HELP next_event_seconds Seconds until the start of the next event.\n" +
+				"# TYPE next_event_seconds gauge\n" +
+				"next_event_seconds 63000\n" +
+				"# HELP in_meeting 1 if an event is in progress.\n" +
+				"# TYPE in_meeting gauge\n" +
+				"in_meeting 1\n",
+		},
+		{
+			name: "not a headline candidate",
+			now:  day.Add(10*time.Hour + 30*time.Minute),
+			opts: render.Options{DropNoAttendeesHeadline: true},
+			expected: "# HELP meetings_today Number of the timed events of the day.\n" +
+				"# TYPE meetings_today gauge\n" +
+				"meetings_today 0\n" +
+				"# HELP in_meeting 1 if an event is in progress.\n" +
+				"# TYPE in_meeting gauge\n" +
+				"in_meeting 0\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := prometheusMetrics(events, tc.now, tc.opts); got != tc.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tc.expected, got)
+			}
+		})
+	}
+}
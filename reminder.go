@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"log"
+	"time"
+)
+
+// reminders sends notifications when an event starts within one of the configured offsets.
+type reminders struct {
+	offsets  []time.Duration
+	notifier notifier
+	// suppressInOutOfOffice disables the notifications during out-of-office events.
+	suppressInOutOfOffice bool
+	// timeLayout is the Go layout of the start time in the notification.
+	timeLayout string
+	// sent contains the start of the events with already sent notifications.
+	sent map[string]time.Time
+}
+
+func (r *reminders) check(events []render.Event, now time.Time) {
+	for key, start := range r.sent {
+		if start.Before(now) {
+			delete(r.sent, key)
+		}
+	}
+	if r.suppressInOutOfOffice && inOutOfOffice(events, now) {
+		return
+	}
+	for _, event := range events {
+		if event.AllDay || event.Declined() || !now.Before(event.Start) {
+			continue
+		}
+		// all the passed offsets are marked, a delayed poll sends one notification instead of one for each offset
+		due := false
+		for _, offset := range r.offsets {
+			key := fmt.Sprintf("%s/%s/%s", event.Raw.Id, event.Start.Format(time.RFC3339), offset)
+			if _, sent := r.sent[key]; sent || now.Before(event.Start.Add(-offset)) {
+				continue
+			}
+			r.sent[key] = event.Start
+			due = true
+		}
+		if !due {
+			continue
+		}
+		err := r.notifier.notify(notification{
+			key:   event.Raw.Id,
+			title: event.Raw.Summary,
+			body:  reminderBody(event, now, r.timeLayout),
+			link:  event.VideoLink(),
+		})
+		if err != nil {
+			log.Printf("couldn't send notification: %v", err)
+		}
+	}
+}
+
+// reminderBody is the text of the notification: start time, countdown and the room or location.
+func reminderBody(event render.Event, now time.Time, layout string) string {
+	body := fmt.Sprintf("%s %s", event.Start.Format(layout), render.Countdown(event.Start.Sub(now)))
+	if room := event.Room(); room != "" {
+		body += "\n" + room
+	} else if event.Raw.Location != "" && event.Raw.Location != event.VideoLink() {
+		body += "\n" + event.Raw.Location
+	}
+	return body
+}
+
+// inOutOfOffice returns true if an out-of-office event is in progress.
+func inOutOfOffice(events []render.Event, now time.Time) bool {
+	for _, event := range events {
+		if event.OutOfOffice() && event.InProgress(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"google.golang.org/api/calendar/v3"
+	"testing"
+	"time"
+)
+
+// recordingNotifier collects the sent notifications.
+type recordingNotifier struct {
+	sent []notification
+}
+
+func (r *recordingNotifier) notify(n notification) error {
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func reminderEvent(id string, summary string, start time.Time, end time.Time) render.Event {
+	return render.NewEvent(&calendar.Event{
+		Id:      id,
+		Summary: summary,
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+	})
+}
+
+func TestRemindersOutOfOffice(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	outOfOffice := reminderEvent("ooo", "Out of office", day.Add(8*time.Hour), day.Add(12*time.Hour))
+	outOfOffice.Raw.EventType = "outOfOffice"
+	events := []render.Event{
+		outOfOffice,
+		reminderEvent("review", "Review", day.Add(10*time.Hour), day.Add(11*time.Hour)),
+		reminderEvent("planning", "Planning", day.Add(14*time.Hour), day.Add(15*time.Hour)),
+	}
+	tests := []struct {
+		name     string
+		suppress bool
+		now      time.Time
+		expected string
+	}{
+		{name: "suppressed during out of office", suppress: true, now: day.Add(9*time.Hour + 55*time.Minute)},
+		{name: "not suppressed", now: day.Add(9*time.Hour + 55*time.Minute), expected: "Review"},
+		{name: "after out of office", suppress: true, now: day.Add(13*time.Hour + 55*time.Minute), expected: "Planning"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &recordingNotifier{}
+			r := reminders{
+				offsets:               []time.Duration{10 * time.Minute},
+				notifier:              n,
+				suppressInOutOfOffice: tc.suppress,
+				timeLayout:            "15:04",
+				sent:                  map[string]time.Time{},
+			}
+			r.check(events, tc.now)
+			var got string
+			for _, sent := range n.sent {
+				got += sent.title
+			}
+			if got != tc.expected {
+				t.Fatalf("expected notification about %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRemindersOffsets(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	start := day.Add(10 * time.Hour)
+	events := []render.Event{reminderEvent("review", "Review", start, start.Add(time.Hour))}
+	tests := []struct {
+		name  string
+		polls []time.Duration
+		sent  []int
+	}{
+		{name: "each offset once", polls: []time.Duration{-11 * time.Minute, -9 * time.Minute, -8 * time.Minute, -4 * time.Minute, -3 * time.Minute}, sent: []int{0, 1, 1, 2, 2}},
+		{name: "delayed poll after both offsets", polls: []time.Duration{-11 * time.Minute, -4 * time.Minute, -3 * time.Minute, -1 * time.Minute}, sent: []int{0, 1, 1, 1}},
+		{name: "first poll after both offsets", polls: []time.Duration{-2 * time.Minute, -1 * time.Minute}, sent: []int{1, 1}},
+		{name: "started", polls: []time.Duration{time.Minute}, sent: []int{0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &recordingNotifier{}
+			r := reminders{
+				offsets:    []time.Duration{10 * time.Minute, 5 * time.Minute},
+				notifier:   n,
+				timeLayout: "15:04",
+				sent:       map[string]time.Time{},
+			}
+			for i, poll := range tc.polls {
+				r.check(events, start.Add(poll))
+				if len(n.sent) != tc.sent[i] {
+					t.Fatalf("poll %d (%s): expected %d notifications, got %d", i, poll, tc.sent[i], len(n.sent))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+)
+
+// newSources returns the Google calendars of the accounts and the calendars of the other providers.
+func newSources(ctx context.Context, authOpts auth.Options, opts runOptions) ([]providers.Source, error) {
+	var res []providers.Source
+	if opts.google {
+		calendars, err := newCalendars(ctx, authOpts, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, cal := range calendars {
+			res = append(res, cal)
+		}
+	}
+
+	if len(opts.icsURLs) > 0 {
+		dir, err := cacheDir(opts.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, url := range opts.icsURLs {
+			res = append(res, providers.NewICSFeed(url, dir, opts.icsRefresh))
+		}
+	}
+	for _, dir := range opts.vdirs {
+		res = append(res, providers.VDir{Path: getConfigDir(dir)})
+	}
+	for _, command := range opts.plugins {
+		res = append(res, providers.Plugin{Command: command})
+	}
+
+	configured, err := providers.ReadConfig(authOpts.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configured.CalDAV {
+		cal, err := providers.NewCalDAV(config)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cal)
+	}
+	for _, config := range configured.Outlook {
+		cal, err := providers.NewOutlook(ctx, authOpts, config)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cal)
+	}
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// stats prints the number and the length of the meetings of today and the current week, and the daily average of the
+// days saved with --persist-metrics.
+func stats(authOpts auth.Options, opts runOptions, perCalendar bool) error {
+	ctx := context.Background()
+
+	first, err := render.ParseWeekday(opts.weekStart)
+	if err != nil {
+		return err
+	}
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	from := render.StartOfWeek(now, first)
+	events, err := fetchRange(ctx, calendars, from, from.AddDate(0, 0, 7), opts)
+	if err != nil {
+		return err
+	}
+
+	today := render.StartOfDay(now)
+	var todays []render.Event
+	for _, event := range events {
+		if !event.Start.Before(today) && event.Start.Before(today.AddDate(0, 0, 1)) {
+			todays = append(todays, event)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "\tmeetings\ttime")
+	writeLoad(w, "today", todays, perCalendar)
+	writeLoad(w, "this week", events, perCalendar)
+
+	dir, err := cacheDir(opts.cacheDir)
+	if err != nil {
+		return err
+	}
+	history, err := readMetrics(filepath.Join(dir, "metrics.csv"))
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 {
+		count, minutes := 0, 0
+		for _, day := range history {
+			count += day.count
+			minutes += day.minutes
+		}
+		average := time.Duration(minutes) * time.Minute / time.Duration(len(history))
+		_, _ = fmt.Fprintf(w, "daily average (%d days)\t%.1f\t%s\n", len(history), float64(count)/float64(len(history)), render.ShortDuration(average))
+	}
+	return w.Flush()
+}
+
+// writeLoad prints the meeting load of the events, followed by the load of each calendar if perCalendar is set.
+func writeLoad(w io.Writer, title string, events []render.Event, perCalendar bool) {
+	count, length := meetingLoad(events)
+	_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", title, count, render.ShortDuration(length))
+	if !perCalendar {
+		return
+	}
+	byCalendar := map[string][]render.Event{}
+	var names []string
+	for _, event := range events {
+		if _, found := byCalendar[event.Calendar]; !found {
+			names = append(names, event.Calendar)
+		}
+		byCalendar[event.Calendar] = append(byCalendar[event.Calendar], event)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		count, length := meetingLoad(byCalendar[name])
+		_, _ = fmt.Fprintf(w, "  %s\t%d\t%s\n", name, count, render.ShortDuration(length))
+	}
+}
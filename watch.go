@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"github.com/zeebo/errs/v2"
+	"math/rand"
+	"os"
+	"time"
+)
+
+type watchOptions struct {
+	interval      time.Duration
+	jitter        time.Duration
+	blink         bool
+	blinkEvery    int
+	remindBefore  []time.Duration
+	notifyBackend string
+	suppressInOOO bool
+	hooks         []string
+}
+
+// watch prints one bar item per line after each refresh, until the process is killed.
+func watch(authOpts auth.Options, opts runOptions, wopts watchOptions) error {
+	ctx := context.Background()
+
+	if opts.format == "i3blocks" {
+		// persistent i3blocks blocks read only the full text from each line
+		return errs.Errorf("i3blocks format can be used only with run, configure the interval of the block instead")
+	}
+
+	if wopts.jitter >= wopts.interval {
+		// the sleep could be zero, polling the API in a busy loop
+		return errs.Errorf("--poll-jitter (%s) should be shorter than --interval (%s)", wopts.jitter, wopts.interval)
+	}
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+
+	var remind *reminders
+	if len(wopts.remindBefore) > 0 {
+		n, err := newNotifier(wopts.notifyBackend)
+		if err != nil {
+			return err
+		}
+		remind = &reminders{
+			offsets:               wopts.remindBefore,
+			notifier:              n,
+			suppressInOutOfOffice: wopts.suppressInOOO,
+			timeLayout:            render.ClockLayout(opts.Options),
+			sent:                  map[string]time.Time{},
+		}
+	}
+
+	var lifecycle *hooks
+	if len(wopts.hooks) > 0 {
+		lifecycle, err = newHooks(wopts.hooks, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var events []render.Event
+	for poll := 0; ; poll++ {
+		now := time.Now()
+		item, refreshed, err := refresh(ctx, calendars, now, opts)
+		if err == nil {
+			events = refreshed
+		}
+		if err == nil && remind != nil {
+			remind.check(events, now)
+		}
+		if lifecycle != nil {
+			lifecycle.check(events, now)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+			item = render.ErrorItem(err)
+		}
+		if wopts.blink {
+			item = blink(item, poll, wopts.blinkEvery)
+		}
+		line, err := formatItem(item, events, now, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(line)
+		fingerprint := providers.VDirFingerprint(opts.vdirs)
+		sleepUntil(time.Now().Add(jitteredInterval(wopts.interval, wopts.jitter, rnd)), func(now time.Time) bool {
+			if lifecycle != nil {
+				lifecycle.check(events, now)
+			}
+			// refresh immediately when the local calendars are changed
+			return len(opts.vdirs) > 0 && providers.VDirFingerprint(opts.vdirs) != fingerprint
+		})
+	}
+}
+
+// sleepUntil waits until the wall clock reaches the deadline, calling tick periodically, or until tick returns true.
+// Unlike time.Sleep, it wakes up shortly after a suspend which is longer than the remaining time.
+func sleepUntil(deadline time.Time, tick func(now time.Time) bool) {
+	deadline = deadline.Round(0)
+	for {
+		now := time.Now().Round(0)
+		if !now.Before(deadline) {
+			return
+		}
+		wait := deadline.Sub(now)
+		if wait > 10*time.Second {
+			wait = 10 * time.Second
+		}
+		time.Sleep(wait)
+		if tick(time.Now()) {
+			return
+		}
+	}
+}
+
+// blink adds the blink class to every other group of polls (blinkEvery long) while the event is in progress.
+func blink(item render.BarItem, poll int, blinkEvery int) render.BarItem {
+	if blinkEvery < 1 {
+		blinkEvery = 1
+	}
+	if item.InProgress && (poll/blinkEvery)%2 == 0 {
+		item.Class = append(item.Class, "blink")
+	}
+	return item
+}
+
+// jitteredInterval returns a random duration from the [interval-jitter, interval+jitter] range.
+func jitteredInterval(interval time.Duration, jitter time.Duration, rnd *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	res := interval - jitter + time.Duration(rnd.Int63n(int64(2*jitter)+1))
+	if res < 0 {
+		return 0
+	}
+	return res
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJitteredInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		jitter   time.Duration
+	}{
+		{name: "no jitter", interval: time.Minute},
+		{name: "small jitter", interval: time.Minute, jitter: 5 * time.Second},
+		{name: "large jitter", interval: time.Minute, jitter: 59 * time.Second},
+		{name: "sub-second", interval: time.Second, jitter: 500 * time.Millisecond},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+			for i := 0; i < 1000; i++ {
+				got := jitteredInterval(tc.interval, tc.jitter, rnd)
+				if got < tc.interval-tc.jitter || got > tc.interval+tc.jitter {
+					t.Fatalf("%s is out of [%s, %s]", got, tc.interval-tc.jitter, tc.interval+tc.jitter)
+				}
+				if got <= 0 {
+					t.Fatalf("interval should be positive, got %s", got)
+				}
+			}
+		})
+	}
+}
+
+func TestJitteredIntervalSpread(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		seen[jitteredInterval(time.Minute, 10*time.Second, rnd)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("jittered intervals should differ, got %v", seen)
+	}
+}
+
+func TestWatchRejectsTooLargeJitter(t *testing.T) {
+	err := watch(auth.Options{ConfigDir: t.TempDir()}, runOptions{}, watchOptions{interval: time.Minute, jitter: time.Minute})
+	if err == nil {
+		t.Fatal("jitter equal to the interval should be rejected")
+	}
+}
+
+func TestBlinkDuringMeeting(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	at := func(hour int, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	events := []render.Event{
+		testEvent("a", at(10, 0).Format(time.RFC3339), at(11, 0).Format(time.RFC3339)),
+		testEvent("b", at(12, 0).Format(time.RFC3339), at(13, 0).Format(time.RFC3339)),
+	}
+	opts := render.Options{GracePeriod: 5 * time.Minute, TimeFormat: "15:04"}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		blinkEvery int
+		expected   string
+	}{
+		{name: "start of the meeting", now: at(10, 1), blinkEvery: 1, expected: "x-x-x-"},
+		{name: "after the grace period", now: at(10, 30), blinkEvery: 1, expected: "x-x-x-"},
+		{name: "slower cadence", now: at(10, 30), blinkEvery: 2, expected: "xx--xx"},
+		{name: "between the meetings", now: at(11, 30), blinkEvery: 1, expected: "------"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			item, err := render.Render(events, tc.now, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			for poll := 0; poll < len(tc.expected); poll++ {
+				if contains(blink(item, poll, tc.blinkEvery).Class, "blink") {
+					got += "x"
+				} else {
+					got += "-"
+				}
+			}
+			if got != tc.expected {
+				t.Fatalf("expected %s, got %s (%s)", tc.expected, got, strings.Join(item.Class, ","))
+			}
+		})
+	}
+}
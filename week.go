@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/elek/waybar-google-calendar-check/pkg/auth"
+	"github.com/elek/waybar-google-calendar-check/pkg/providers"
+	"github.com/elek/waybar-google-calendar-check/pkg/render"
+	"strings"
+	"time"
+)
+
+// week prints the events of the current week, grouped by weekday.
+func week(authOpts auth.Options, opts runOptions) error {
+	ctx := context.Background()
+
+	calendars, err := newSources(ctx, authOpts, opts)
+	if err != nil {
+		return err
+	}
+	overview, err := weekOverview(ctx, calendars, time.Now(), opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(overview)
+	return nil
+}
+
+// weekOverview returns the events of the week which contains now, grouped by weekday.
+func weekOverview(ctx context.Context, calendars []providers.Source, now time.Time, opts runOptions) (string, error) {
+	first, err := render.ParseWeekday(opts.weekStart)
+	if err != nil {
+		return "", err
+	}
+	from := render.StartOfWeek(now, first)
+	events, err := fetchRange(ctx, calendars, from, from.AddDate(0, 0, 7), opts)
+	if err != nil {
+		return "", err
+	}
+	overview, err := render.Week(events, from, now, opts.Options, opts.ignoreWeekends)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(overview, "\n"), nil
+}